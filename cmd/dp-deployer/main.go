@@ -10,7 +10,9 @@ import (
 	"github.com/ONSdigital/dp-deployer/engine"
 	"github.com/ONSdigital/dp-deployer/handler/deployment"
 	"github.com/ONSdigital/dp-deployer/handler/secret"
+	"github.com/ONSdigital/dp-deployer/nomadclient"
 	"github.com/ONSdigital/dp-deployer/queue"
+	"github.com/ONSdigital/dp-deployer/s3"
 	"github.com/ONSdigital/dp-healthcheck/healthcheck"
 	nomad "github.com/ONSdigital/dp-nomad"
 	s3client "github.com/ONSdigital/dp-s3"
@@ -18,6 +20,7 @@ import (
 	"github.com/ONSdigital/dp-net/http"
 	"github.com/ONSdigital/log.go/v2/log"
 	"github.com/gorilla/mux"
+	vaultapi "github.com/hashicorp/vault/api"
 	"github.com/pkg/errors"
 )
 
@@ -50,6 +53,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Create vault api client for the vault secret store
+	var vaultAPI *vaultapi.Client
+	vaultAPI, err = vaultapi.NewClient(&vaultapi.Config{Address: cfg.VaultAddr, MaxRetries: 3})
+	if err != nil {
+		log.Fatal(ctx, "error creating vault api client", err)
+		os.Exit(1)
+	}
+	vaultAPI.SetToken(cfg.VaultToken)
+
 	// Create S3 secrets client
 	var secretsClient *s3client.S3
 	secretsClient, err = s3client.NewClient(cfg.AWSRegion, cfg.SecretsBucketName)
@@ -74,8 +86,46 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Authorise requests to the default cluster with Nomad tokens from Vault
+	var tokens *nomadclient.VaultTokens
+	var token nomadclient.TokenSource = nomadclient.StaticToken(cfg.NomadToken)
+	if len(cfg.NomadVaultRole) > 0 {
+		tokens = nomadclient.NewVaultTokens(vaultAPI.Logical(), cfg.NomadVaultMount)
+		token = tokens.Role(cfg.NomadVaultRole)
+	}
+
 	// TODO: remove once new queue implemented fully
-	oldHandler, err := initHandlersOld(cfg, vc, deploymentsClient, secretsClient, nomadClient)
+	stores := map[string]secret.SecretStore{
+		"vault": secret.NewVaultStore(vaultAPI.Logical()),
+		"nomad": secret.NewNomadStore(nomadClient, token, cfg.NomadVariablesPrefix),
+	}
+
+	// Create secret snapshot store
+	var snapshots secret.SnapshotStore
+	if len(cfg.SecretSnapshotBucketName) > 0 {
+		var snapshotsClient *s3client.Uploader
+		snapshotsClient, err = s3client.NewUploader(cfg.AWSRegion, cfg.SecretSnapshotBucketName)
+		if err != nil {
+			log.Fatal(ctx, "error creating S3 snapshots client", err)
+			os.Exit(1)
+		}
+		snapshots = secret.NewS3SnapshotStore(snapshotsClient)
+	} else if len(cfg.SecretSnapshotDir) > 0 {
+		snapshots = secret.NewFileSnapshotStore(cfg.SecretSnapshotDir)
+	}
+
+	// Create clients for the Nomad cluster registry
+	clusters, err := initClusters(cfg, nomadClient, token, tokens, vaultAPI.Logical())
+	if err != nil {
+		log.Fatal(ctx, "error creating nomad cluster clients", err)
+		os.Exit(1)
+	}
+
+	// Share the deployment between both queues, so that a cancel message from
+	// either queue reaches the messages in flight from the other
+	d := deployment.New(cfg, deploymentsClient, clusters)
+
+	oldHandler, drift, err := initHandlersOld(cfg, d, stores, snapshots, secretsClient)
 	if err != nil {
 		log.Fatal(ctx, "failed to initialise handlers", err)
 		os.Exit(1)
@@ -87,7 +137,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	h, err := initHandlers(cfg, vc, deploymentsClient, secretsClient, nomadClient)
+	h, err := initHandlers(cfg, vc, d, secretsClient)
 	if err != nil {
 		log.Fatal(ctx, "failed to initialise handlers", err)
 		os.Exit(1)
@@ -99,7 +149,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	hc, err := startHealthChecks(ctx, cfg, vc, secretsClient, deploymentsClient, nomadClient)
+	hc, err := startHealthChecks(ctx, cfg, vc, secretsClient, deploymentsClient, clusters, drift)
 	if err != nil {
 		log.Fatal(ctx, "failed to start healthchecks", err)
 		os.Exit(1)
@@ -120,6 +170,16 @@ func main() {
 		q.Start(ctx)
 	}()
 
+	if drift != nil {
+		go drift.Start(ctx)
+	}
+
+	for _, c := range clusters {
+		if c.Watcher != nil {
+			go c.Watcher.Start(ctx)
+		}
+	}
+
 	// Create and start http server for healthcheck
 	httpServer := http.NewServer(cfg.BindAddr, r)
 	go func() {
@@ -154,6 +214,15 @@ func main() {
 
 		e.Close()
 
+		// Revoke the Nomad tokens read from Vault
+		revoked := make(map[*nomadclient.VaultTokens]bool)
+		for _, c := range clusters {
+			if c.Tokens != nil && !revoked[c.Tokens] {
+				c.Tokens.Revoke(shutdownContext)
+				revoked[c.Tokens] = true
+			}
+		}
+
 		shutdownCtxCancel()
 	}()
 
@@ -169,27 +238,92 @@ func main() {
 }
 
 // TODO: remove once new queue implemented fully
-func initHandlersOld(cfg *config.Configuration, vc *vault.Client, deploymentsClient *s3client.S3, secretsClient *s3client.S3, nomadClient *nomad.Client) (map[string]engine.HandlerFunc, error) {
-	d := deployment.New(cfg, deploymentsClient, nomadClient)
+func initHandlersOld(cfg *config.Configuration, d *deployment.Deployment, stores map[string]secret.SecretStore, snapshots secret.SnapshotStore, secretsClient *s3client.S3) (map[string]engine.HandlerFunc, *secret.DriftDetector, error) {
+	var restarter secret.Restarter
+	if cfg.SecretRestartDependents {
+		restarter = d
+	}
 
-	s, err := secret.New(cfg, vc, secretsClient)
+	s, err := secret.New(cfg, stores, secretsClient, restarter, snapshots)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	var drift *secret.DriftDetector
+	if cfg.SecretDriftInterval > 0 {
+		drift = secret.NewDriftDetector(s, s3.NewLister(secretsClient.Session(), cfg.SecretsBucketName), cfg.SecretDriftInterval, cfg.SecretDriftHeal)
 	}
 
 	return map[string]engine.HandlerFunc{
-		"deployment": d.Handler,
-		"secret":     s.Handler,
-	}, nil
+		"cancel":         d.CancelHandler,
+		"deployment":     d.Handler,
+		"dispatch":       d.DispatchHandler,
+		"plan":           d.PlanHandler,
+		"restart":        d.RestartHandler,
+		"scale":          d.ScaleHandler,
+		"secret":         s.Handler,
+		"secret-restore": s.RestoreHandler,
+		"stop":           d.StopHandler,
+	}, drift, nil
 }
 
-func initHandlers(cfg *config.Configuration, vc *vault.Client, deploymentsClient *s3client.S3, secretsClient *s3client.S3, nomadClient *nomad.Client) (queue.HandlerFunc, error) {
-	d := deployment.New(cfg, deploymentsClient, nomadClient)
-
+func initHandlers(cfg *config.Configuration, vc *vault.Client, d *deployment.Deployment, secretsClient *s3client.S3) (queue.HandlerFunc, error) {
 	return d.NewHandler, nil
 }
 
-func startHealthChecks(ctx context.Context, cfg *config.Configuration, vaultChecker *vault.Client, s3sChecker *s3client.S3, s3dChecker *s3client.S3, nomadClient *nomad.Client) (*healthcheck.HealthCheck, error) {
+// initClusters returns the default cluster at NOMAD_ENDPOINT, authorised with
+// tokens from the source, and the clusters in the NOMAD_CLUSTERS registry. When
+// NOMAD_VAULT_ROLE is set, registry clusters are authorised with tokens from
+// the Nomad secrets engine at their VaultMount, or NOMAD_VAULT_MOUNT, instead of
+// their static tokens.
+func initClusters(cfg *config.Configuration, nomadClient *nomad.Client, token nomadclient.TokenSource, tokens *nomadclient.VaultTokens, vc nomadclient.VaultClient) (map[string]*deployment.Cluster, error) {
+	apiClient, err := newAPIClient(cfg, cfg.NomadEndpoint, cfg.NomadCACert, token, cfg.NomadTLSSkipVerify)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating api client for nomad")
+	}
+	clusters := map[string]*deployment.Cluster{
+		deployment.DefaultCluster: deployment.NewCluster(cfg, cfg.NomadEndpoint, token, nomadClient, apiClient),
+	}
+	clusters[deployment.DefaultCluster].Tokens = tokens
+
+	for name, c := range cfg.NomadClusters {
+		client, err := nomad.NewClient(c.Endpoint, c.CACert, c.TLSSkipVerify)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error creating client for nomad cluster %s", name)
+		}
+		var clusterTokens *nomadclient.VaultTokens
+		var token nomadclient.TokenSource = nomadclient.StaticToken(c.Token)
+		if tokens != nil {
+			clusterTokens = tokens
+			if len(c.VaultMount) > 0 && c.VaultMount != cfg.NomadVaultMount {
+				clusterTokens = nomadclient.NewVaultTokens(vc, c.VaultMount)
+			}
+			token = clusterTokens.Role(cfg.NomadVaultRole)
+		}
+		apiClient, err := newAPIClient(cfg, c.Endpoint, c.CACert, token, c.TLSSkipVerify)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error creating api client for nomad cluster %s", name)
+		}
+		clusters[name] = deployment.NewCluster(cfg, c.Endpoint, token, client, apiClient)
+		clusters[name].Tokens = clusterTokens
+	}
+	return clusters, nil
+}
+
+// newAPIClient returns a typed Nomad API client for the endpoint that is
+// authorised with tokens from the source. Its HTTP client does not retry
+// requests itself, as the API client retries transient errors with the
+// configured backoff.
+func newAPIClient(cfg *config.Configuration, endpoint, caCert string, token nomadclient.TokenSource, tlsSkipVerify bool) (nomadclient.Client, error) {
+	c, err := nomad.NewClient(endpoint, caCert, tlsSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	c.Client.SetMaxRetries(0)
+	return nomadclient.New(endpoint, "", c.Client, cfg.NomadRetries, cfg.NomadRetryBackoff).WithToken(token), nil
+}
+
+func startHealthChecks(ctx context.Context, cfg *config.Configuration, vaultChecker *vault.Client, s3sChecker *s3client.S3, s3dChecker *s3client.S3, clusters map[string]*deployment.Cluster, drift *secret.DriftDetector) (*healthcheck.HealthCheck, error) {
 
 	// Create healthcheck object with versionInfo
 	versionInfo, err := healthcheck.NewVersionInfo(BuildTime, GitCommit, Version)
@@ -210,8 +344,20 @@ func startHealthChecks(ctx context.Context, cfg *config.Configuration, vaultChec
 		return nil, errors.Wrap(err, "error adding check for S3 deployments")
 	}
 
-	if err := hc.AddCheck("Nomad", nomadClient.Checker); err != nil {
-		return nil, errors.Wrap(err, "error adding check for nomad")
+	for name, c := range clusters {
+		checkName := "Nomad"
+		if name != deployment.DefaultCluster {
+			checkName += " " + name
+		}
+		if err := hc.AddCheck(checkName, c.Checker); err != nil {
+			return nil, errors.Wrapf(err, "error adding check for nomad cluster %s", checkName)
+		}
+	}
+
+	if drift != nil {
+		if err := hc.AddCheck("Secret drift", drift.Checker); err != nil {
+			return nil, errors.Wrap(err, "error adding check for secret drift")
+		}
 	}
 
 	// Start healthcheck
@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -9,30 +10,102 @@ import (
 
 // Configuration structure whiich holds information for configuring the deployer
 type Configuration struct {
-	ConsumerQueue              string        `envconfig:"CONSUMER_QUEUE"`
-	ConsumerQueueURL           string        `envconfig:"CONSUMER_QUEUE_URL"`
-	ProducerQueue              string        `envconfig:"PRODUCER_QUEUE"`
-	VerificationKey            string        `envconfig:"VERIFICATION_KEY" json:"-"`
-	DeploymentRoot             string        `envconfig:"DEPLOYMENT_ROOT"`
-	NomadEndpoint              string        `envconfig:"NOMAD_ENDPOINT"`
-	NomadToken                 string        `envconfig:"NOMAD_TOKEN" json:"-"`
-	NomadCACert                string        `envconfig:"NOMAD_CA_CERT" json:"-"`
-	NomadTLSSkipVerify         bool          `envconfig:"NOMAD_TLS_SKIP_VERIFY"`
-	DeploymentTimeout          time.Duration `envconfig:"DEPLOYMENT_TIMEOUT"`
-	BindAddr                   string        `envconfig:"BIND_ADDR"`
-	HealthcheckInterval        time.Duration `envconfig:"HEALTHCHECK_INTERVAL"`
-	HealthcheckCriticalTimeout time.Duration `envconfig:"HEALTHCHECK_CRITICAL_TIMEOUT"`
-	PrivateKey                 string        `envconfig:"PRIVATE_KEY" json:"-"`
-	VaultAddr                  string        `envconfig:"VAULT_ADDR"`
-	VaultToken                 string        `envconfig:"VAULT_TOKEN" json:"-"`
-	AWSRegion                  string        `envconfig:"AWS_REGION"`
-	SecretsBucketName          string        `envconfig:"SECRETS_BUCKET_NAME"`
-	DeploymentsBucketName      string        `envconfig:"DEPLOYMENTS_BUCKET_NAME"`
-	GracefulShutdownTimeout    time.Duration `envconfig:"GRACEFUL_SHUTDOWN_TIMEOUT"`
-	ECR_URL                    string        `envconfig:"ECR_URL"`
-	ArtifactSource             string        `envconfig:"ARTIFACT_SOURCE"`
-	ConsumerQueueNew           string        `envconfig:"CONSUMER_QUEUE_NEW"`
-	ConsumerQueueURLNew        string        `envconfig:"CONSUMER_QUEUE_URL_NEW"`
+	ConsumerQueue              string            `envconfig:"CONSUMER_QUEUE"`
+	ConsumerQueueURL           string            `envconfig:"CONSUMER_QUEUE_URL"`
+	ProducerQueue              string            `envconfig:"PRODUCER_QUEUE"`
+	VerificationKey            string            `envconfig:"VERIFICATION_KEY" json:"-"`
+	DeploymentRoot             string            `envconfig:"DEPLOYMENT_ROOT"`
+	NomadEndpoint              string            `envconfig:"NOMAD_ENDPOINT"`
+	NomadToken                 string            `envconfig:"NOMAD_TOKEN" json:"-"`
+	NomadCACert                string            `envconfig:"NOMAD_CA_CERT" json:"-"`
+	NomadTLSSkipVerify         bool              `envconfig:"NOMAD_TLS_SKIP_VERIFY"`
+	NomadEventStream           bool              `envconfig:"NOMAD_EVENT_STREAM"`
+	NomadNamespace             string            `envconfig:"NOMAD_NAMESPACE"`
+	NomadRegion                string            `envconfig:"NOMAD_REGION"`
+	NomadDatacenters           []string          `envconfig:"NOMAD_DATACENTERS"`
+	NomadClusters              NomadClusters     `envconfig:"NOMAD_CLUSTERS"`
+	NomadRetries               int               `envconfig:"NOMAD_RETRIES"`
+	NomadRetryBackoff          time.Duration     `envconfig:"NOMAD_RETRY_BACKOFF"`
+	NomadVaultMount            string            `envconfig:"NOMAD_VAULT_MOUNT"`
+	NomadVaultRole             string            `envconfig:"NOMAD_VAULT_ROLE"`
+	NomadVaultNamespaceRoles   map[string]string `envconfig:"NOMAD_VAULT_NAMESPACE_ROLES"`
+	NomadVaultSignerRoles      map[string]string `envconfig:"NOMAD_VAULT_SIGNER_ROLES"`
+	DeploymentTimeout          time.Duration     `envconfig:"DEPLOYMENT_TIMEOUT"`
+	RollbackPolicy             string            `envconfig:"ROLLBACK_POLICY"`
+	CanaryVerifyPeriod         time.Duration     `envconfig:"CANARY_VERIFY_PERIOD"`
+	PlanMaxDestructiveUpdates  int               `envconfig:"PLAN_MAX_DESTRUCTIVE_UPDATES"`
+	PlanMaxStops               int               `envconfig:"PLAN_MAX_STOPS"`
+	PlanMaxResourceGrowth      float64           `envconfig:"PLAN_MAX_RESOURCE_GROWTH"`
+	PlanDenyTypeChange         bool              `envconfig:"PLAN_DENY_TYPE_CHANGE"`
+	Environment                string            `envconfig:"ENVIRONMENT"`
+	JobVariables               map[string]string `envconfig:"JOB_VARIABLES"`
+	BindAddr                   string            `envconfig:"BIND_ADDR"`
+	HealthcheckInterval        time.Duration     `envconfig:"HEALTHCHECK_INTERVAL"`
+	HealthcheckCriticalTimeout time.Duration     `envconfig:"HEALTHCHECK_CRITICAL_TIMEOUT"`
+	PrivateKey                 string            `envconfig:"PRIVATE_KEY" json:"-"`
+	VaultAddr                  string            `envconfig:"VAULT_ADDR"`
+	VaultToken                 string            `envconfig:"VAULT_TOKEN" json:"-"`
+	SecretStore                string            `envconfig:"SECRET_STORE"`
+	SecretStoreRules           map[string]string `envconfig:"SECRET_STORE_RULES"`
+	NomadVariablesPrefix       string            `envconfig:"NOMAD_VARIABLES_PREFIX"`
+	SecretRestartDependents    bool              `envconfig:"SECRET_RESTART_DEPENDENTS"`
+	SecretSnapshotDir          string            `envconfig:"SECRET_SNAPSHOT_DIR"`
+	SecretSnapshotBucketName   string            `envconfig:"SECRET_SNAPSHOT_BUCKET_NAME"`
+	SecretDriftInterval        time.Duration     `envconfig:"SECRET_DRIFT_INTERVAL"`
+	SecretDriftHeal            bool              `envconfig:"SECRET_DRIFT_HEAL"`
+	AWSRegion                  string            `envconfig:"AWS_REGION"`
+	SecretsBucketName          string            `envconfig:"SECRETS_BUCKET_NAME"`
+	DeploymentsBucketName      string            `envconfig:"DEPLOYMENTS_BUCKET_NAME"`
+	GracefulShutdownTimeout    time.Duration     `envconfig:"GRACEFUL_SHUTDOWN_TIMEOUT"`
+	ECR_URL                    string            `envconfig:"ECR_URL"`
+	RequireArtifactDigests     bool              `envconfig:"REQUIRE_ARTIFACT_DIGESTS"`
+	BundleMaxSize              int64             `envconfig:"BUNDLE_MAX_SIZE"`
+	BundleMaxFileSize          int64             `envconfig:"BUNDLE_MAX_FILE_SIZE"`
+	KeepFailedWorkspaces       bool              `envconfig:"KEEP_FAILED_WORKSPACES"`
+	JobFailurePolicy           string            `envconfig:"JOB_FAILURE_POLICY"`
+	DiagnosticLogLines         int               `envconfig:"DIAGNOSTIC_LOG_LINES"`
+	ProtectedJobs              []string          `envconfig:"PROTECTED_JOBS"`
+	ScaleMinCount              int               `envconfig:"SCALE_MIN_COUNT"`
+	ScaleMaxCount              int               `envconfig:"SCALE_MAX_COUNT"`
+	RestartBatchSize           int               `envconfig:"RESTART_BATCH_SIZE"`
+	RestartBatchPause          time.Duration     `envconfig:"RESTART_BATCH_PAUSE"`
+	ArtifactSource             string            `envconfig:"ARTIFACT_SOURCE"`
+	ConsumerQueueNew           string            `envconfig:"CONSUMER_QUEUE_NEW"`
+	ConsumerQueueURLNew        string            `envconfig:"CONSUMER_QUEUE_URL_NEW"`
+}
+
+// NomadCluster represents a named Nomad cluster that messages can be deployed
+// to instead of the one at NOMAD_ENDPOINT.
+type NomadCluster struct {
+	Endpoint      string
+	CACert        string `json:"-"`
+	Token         string `json:"-"`
+	TLSSkipVerify bool
+	VaultMount    string
+}
+
+// NomadClusters is a registry of named Nomad clusters, decoded from a JSON
+// object of cluster names to their config.
+type NomadClusters map[string]NomadCluster
+
+// Decode implements envconfig.Decoder. The CA cert and token are decoded even
+// though they are left out when the config is logged.
+func (c *NomadClusters) Decode(value string) error {
+	var clusters map[string]struct {
+		Endpoint      string
+		CACert        string
+		Token         string
+		TLSSkipVerify bool
+		VaultMount    string
+	}
+	if err := json.Unmarshal([]byte(value), &clusters); err != nil {
+		return err
+	}
+	*c = make(NomadClusters, len(clusters))
+	for name, cluster := range clusters {
+		(*c)[name] = NomadCluster(cluster)
+	}
+	return nil
 }
 
 var cfg *Configuration
@@ -53,23 +126,80 @@ func Get() (*Configuration, error) {
 		NomadToken:                 "",
 		NomadCACert:                "",
 		NomadTLSSkipVerify:         false,
+		NomadEventStream:           true,
+		NomadNamespace:             "",
+		NomadRegion:                "eu",
+		NomadDatacenters:           []string{"eu-west-1"},
+		NomadClusters:              nil,
+		NomadRetries:               3,
+		NomadRetryBackoff:          time.Second,
+		NomadVaultMount:            "nomad",
+		NomadVaultRole:             "",
+		NomadVaultNamespaceRoles:   nil,
+		NomadVaultSignerRoles:      nil,
 		DeploymentTimeout:          time.Second * 60 * 20,
+		RollbackPolicy:             "none",
+		CanaryVerifyPeriod:         0,
+		PlanMaxDestructiveUpdates:  0,
+		PlanMaxStops:               0,
+		PlanMaxResourceGrowth:      0,
+		PlanDenyTypeChange:         false,
+		Environment:                "",
+		JobVariables:               nil,
 		BindAddr:                   ":24300",
 		HealthcheckInterval:        time.Second * 30,
 		HealthcheckCriticalTimeout: time.Second * 10,
 		PrivateKey:                 "",
 		VaultAddr:                  "http://localhost:8200",
 		VaultToken:                 "",
+		SecretStore:                "vault",
+		SecretStoreRules:           nil,
+		NomadVariablesPrefix:       "nomad/jobs",
+		SecretRestartDependents:    false,
+		SecretSnapshotDir:          "",
+		SecretSnapshotBucketName:   "",
+		SecretDriftInterval:        0,
+		SecretDriftHeal:            false,
 		AWSRegion:                  "eu-west-1",
 		SecretsBucketName:          "",
 		DeploymentsBucketName:      "",
 		GracefulShutdownTimeout:    time.Second * 5,
 		ECR_URL:                    "",
+		RequireArtifactDigests:     false,
+		BundleMaxSize:              512 << 20,
+		BundleMaxFileSize:          128 << 20,
+		KeepFailedWorkspaces:       false,
+		JobFailurePolicy:           "rollback",
+		DiagnosticLogLines:         20,
+		ProtectedJobs:              []string{"dp-deployer"},
+		ScaleMinCount:              1,
+		ScaleMaxCount:              20,
+		RestartBatchSize:           1,
+		RestartBatchPause:          10 * time.Second,
 		ArtifactSource:             "",
 		ConsumerQueueNew:           "",
 		ConsumerQueueURLNew:        "",
 	}
-	return cfg, envconfig.Process("", cfg)
+	if err := envconfig.Process("", cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// validate rejects policies that would otherwise silently fall back to their
+// default behaviour.
+func (c *Configuration) validate() error {
+	switch c.RollbackPolicy {
+	case "none", "auto", "always":
+	default:
+		return fmt.Errorf("invalid ROLLBACK_POLICY %q: must be none, auto or always", c.RollbackPolicy)
+	}
+	switch c.JobFailurePolicy {
+	case "rollback", "stop":
+	default:
+		return fmt.Errorf("invalid JOB_FAILURE_POLICY %q: must be rollback or stop", c.JobFailurePolicy)
+	}
+	return nil
 }
 
 // String is implemented to prevent senstve fields being logged.
@@ -28,18 +28,56 @@ func TestSpec(t *testing.T) {
 				So(cfg.NomadToken, ShouldEqual, "")
 				So(cfg.NomadCACert, ShouldEqual, "")
 				So(cfg.NomadTLSSkipVerify, ShouldBeFalse)
+				So(cfg.NomadEventStream, ShouldBeTrue)
+				So(cfg.NomadNamespace, ShouldEqual, "")
+				So(cfg.NomadRegion, ShouldEqual, "eu")
+				So(cfg.NomadDatacenters, ShouldResemble, []string{"eu-west-1"})
+				So(cfg.NomadClusters, ShouldBeEmpty)
+				So(cfg.NomadRetries, ShouldEqual, 3)
+				So(cfg.NomadRetryBackoff, ShouldEqual, time.Second)
+				So(cfg.NomadVaultMount, ShouldEqual, "nomad")
+				So(cfg.NomadVaultRole, ShouldEqual, "")
+				So(cfg.NomadVaultNamespaceRoles, ShouldBeNil)
+				So(cfg.NomadVaultSignerRoles, ShouldBeNil)
 				So(cfg.DeploymentTimeout, ShouldEqual, time.Second*60*20)
+				So(cfg.RollbackPolicy, ShouldEqual, "none")
+				So(cfg.CanaryVerifyPeriod, ShouldEqual, 0)
+				So(cfg.PlanMaxDestructiveUpdates, ShouldEqual, 0)
+				So(cfg.PlanMaxStops, ShouldEqual, 0)
+				So(cfg.PlanMaxResourceGrowth, ShouldEqual, 0)
+				So(cfg.PlanDenyTypeChange, ShouldBeFalse)
+				So(cfg.Environment, ShouldEqual, "")
+				So(cfg.JobVariables, ShouldBeNil)
 				So(cfg.BindAddr, ShouldEqual, ":24300")
 				So(cfg.HealthcheckInterval, ShouldEqual, time.Second*30)
 				So(cfg.HealthcheckCriticalTimeout, ShouldEqual, time.Second*10)
 				So(cfg.PrivateKey, ShouldEqual, "")
 				So(cfg.VaultAddr, ShouldEqual, "http://localhost:8200")
 				So(cfg.VaultToken, ShouldEqual, "")
+				So(cfg.SecretStore, ShouldEqual, "vault")
+				So(cfg.SecretStoreRules, ShouldBeEmpty)
+				So(cfg.NomadVariablesPrefix, ShouldEqual, "nomad/jobs")
+				So(cfg.SecretRestartDependents, ShouldBeFalse)
+				So(cfg.SecretSnapshotDir, ShouldEqual, "")
+				So(cfg.SecretSnapshotBucketName, ShouldEqual, "")
+				So(cfg.SecretDriftInterval, ShouldEqual, 0)
+				So(cfg.SecretDriftHeal, ShouldBeFalse)
 				So(cfg.AWSRegion, ShouldEqual, "eu-west-1")
 				So(cfg.SecretsBucketName, ShouldEqual, "")
 				So(cfg.DeploymentsBucketName, ShouldEqual, "")
 				So(cfg.GracefulShutdownTimeout, ShouldEqual, 5*time.Second)
 				So(cfg.ECR_URL, ShouldEqual, "")
+				So(cfg.RequireArtifactDigests, ShouldBeFalse)
+				So(cfg.BundleMaxSize, ShouldEqual, 512<<20)
+				So(cfg.BundleMaxFileSize, ShouldEqual, 128<<20)
+				So(cfg.KeepFailedWorkspaces, ShouldBeFalse)
+				So(cfg.JobFailurePolicy, ShouldEqual, "rollback")
+				So(cfg.DiagnosticLogLines, ShouldEqual, 20)
+				So(cfg.ProtectedJobs, ShouldResemble, []string{"dp-deployer"})
+				So(cfg.ScaleMinCount, ShouldEqual, 1)
+				So(cfg.ScaleMaxCount, ShouldEqual, 20)
+				So(cfg.RestartBatchSize, ShouldEqual, 1)
+				So(cfg.RestartBatchPause, ShouldEqual, 10*time.Second)
 				So(cfg.ArtifactSource, ShouldEqual, "")
 				So(cfg.ConsumerQueueNew, ShouldEqual, "")
 				So(cfg.ConsumerQueueURLNew, ShouldEqual, "")
@@ -47,3 +85,57 @@ func TestSpec(t *testing.T) {
 		})
 	})
 }
+
+func TestPolicies(t *testing.T) {
+	Convey("Given an environment with policies set", t, func() {
+		os.Clearenv()
+		cfg = nil
+
+		Convey("When the policies are known they are used", func() {
+			os.Setenv("ROLLBACK_POLICY", "auto")
+			os.Setenv("JOB_FAILURE_POLICY", "stop")
+			c, err := Get()
+			So(err, ShouldBeNil)
+			So(c.RollbackPolicy, ShouldEqual, "auto")
+			So(c.JobFailurePolicy, ShouldEqual, "stop")
+		})
+
+		Convey("When the rollback policy is unknown an error is returned", func() {
+			os.Setenv("ROLLBACK_POLICY", "sometimes")
+			_, err := Get()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "ROLLBACK_POLICY")
+		})
+
+		Convey("When the job failure policy is unknown an error is returned", func() {
+			os.Setenv("JOB_FAILURE_POLICY", "ignore")
+			_, err := Get()
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "JOB_FAILURE_POLICY")
+		})
+
+		Reset(func() {
+			os.Clearenv()
+			cfg = nil
+		})
+	})
+}
+
+func TestNomadClusters(t *testing.T) {
+	Convey("Given a JSON registry of nomad clusters", t, func() {
+		var clusters NomadClusters
+		err := clusters.Decode(`{"dr": {"Endpoint": "https://nomad.dr:4646", "CACert": "/etc/nomad/ca.pem", "Token": "secret", "TLSSkipVerify": true, "VaultMount": "nomad-dr"}}`)
+
+		Convey("Then the clusters are decoded with their CA cert and token", func() {
+			So(err, ShouldBeNil)
+			So(clusters, ShouldResemble, NomadClusters{"dr": {Endpoint: "https://nomad.dr:4646", CACert: "/etc/nomad/ca.pem", Token: "secret", TLSSkipVerify: true, VaultMount: "nomad-dr"}})
+		})
+
+		Convey("And the CA cert and token are not logged", func() {
+			s := Configuration{NomadClusters: clusters}.String()
+			So(s, ShouldContainSubstring, "https://nomad.dr:4646")
+			So(s, ShouldNotContainSubstring, "secret")
+			So(s, ShouldNotContainSubstring, "ca.pem")
+		})
+	})
+}
@@ -59,13 +59,43 @@ type Engine struct {
 	wg        sync.WaitGroup
 }
 
+// Job represents one of the jobs deployed by a message, with the artifact its
+// job file is in and the jobs that must be deployed before it.
+type Job struct {
+	Name      string
+	Artifact  string   `json:",omitempty"`
+	DependsOn []string `json:",omitempty"`
+}
+
 // Message represents a message that has been consumed.
 type Message struct {
-	Artifacts []string
-	Bucket    string
-	ID        string `json:"-"`
-	Service   string
-	Type      string
+	Artifacts    []string
+	Bucket       string
+	Cluster      string            `json:",omitempty"`
+	Count        *int              `json:",omitempty"`
+	Datacenters  []string          `json:",omitempty"`
+	Digests      map[string]string `json:",omitempty"`
+	ForceRun     bool              `json:",omitempty"`
+	Group        string            `json:",omitempty"`
+	ID           string            `json:"-"`
+	Jobs         []Job             `json:",omitempty"`
+	JobSpec      []byte            `json:"-"`
+	MessageID    string            `json:",omitempty"`
+	Meta         map[string]string `json:",omitempty"`
+	Namespace    string            `json:",omitempty"`
+	Result       interface{}       `json:"-"`
+	Service      string
+	Signer       string            `json:"-"`
+	Snapshot     string            `json:",omitempty"`
+	Override     bool              `json:",omitempty"`
+	Payload      string            `json:",omitempty"`
+	Placeholders map[string]string `json:",omitempty"`
+	Purge        bool              `json:",omitempty"`
+	Region       string            `json:",omitempty"`
+	Store        string            `json:",omitempty"`
+	Type         string
+	Variables    map[string]string `json:",omitempty"`
+	Workspace    string            `json:"-"`
 }
 
 // HandlerFunc represents a function that is applied to a consumed message.
@@ -74,6 +104,7 @@ type HandlerFunc func(context.Context, *Message) error
 type response struct {
 	Error   *responseError `json:"Error,omitempty"`
 	ID      string
+	Result  interface{} `json:"Result,omitempty"`
 	Success bool
 }
 
@@ -177,17 +208,17 @@ func (e *Engine) handle(ctx context.Context, rawMsg *ssqs.Message) {
 			<-e.semaphore
 		}()
 
-		m, err := e.verifyMessage(rawMsg)
+		m, signer, err := e.verifyMessage(rawMsg)
 		if err != nil {
 			log.Error(ctx, "handle(), e.verifyMessage(rawMsg) error", err)
-			e.postHandle(ctx, rawMsg, err)
+			e.postHandle(ctx, rawMsg, nil, err)
 			return
 		}
 
-		engMsg := Message{ID: rawMsg.ID}
+		engMsg := Message{ID: rawMsg.ID, Signer: signer}
 		if err := json.Unmarshal(m, &engMsg); err != nil {
 			log.Error(ctx, "handle(), json.Unmarshal() error", err)
-			e.postHandle(ctx, rawMsg, err)
+			e.postHandle(ctx, rawMsg, nil, err)
 			return
 		}
 
@@ -195,25 +226,25 @@ func (e *Engine) handle(ctx context.Context, rawMsg *ssqs.Message) {
 		var ok bool
 		if handlerFunc, ok = e.handlers[engMsg.Type]; !ok {
 			log.Error(ctx, "handle(), e.handlers[engMsg.Type] error", err)
-			e.postHandle(ctx, rawMsg, &MissingHandlerError{engMsg.Type})
+			e.postHandle(ctx, rawMsg, nil, &MissingHandlerError{engMsg.Type})
 			return
 		}
 		if err := handlerFunc(ctx, &engMsg); err != nil {
 			log.Error(ctx, "handle(), handlerFunc() error", err)
-			e.postHandle(ctx, rawMsg, err)
+			e.postHandle(ctx, rawMsg, engMsg.Result, err)
 			return
 		}
 
-		e.postHandle(ctx, rawMsg, nil)
+		e.postHandle(ctx, rawMsg, engMsg.Result, nil)
 	}()
 }
 
-func (e *Engine) postHandle(ctx context.Context, msg *ssqs.Message, err error) {
+func (e *Engine) postHandle(ctx context.Context, msg *ssqs.Message, res interface{}, err error) {
 	if err != nil {
 		ErrHandler(ctx, "post handle error", err)
 	}
 
-	result := &response{ID: msg.ID, Success: err == nil}
+	result := &response{ID: msg.ID, Result: res, Success: err == nil}
 	if err != nil {
 		result.Error = &responseError{Data: err, Message: err.Error()}
 	}
@@ -271,13 +302,16 @@ func (e *Engine) sendMessage(ctx context.Context, body string) error {
 	return nil
 }
 
-func (e *Engine) verifyMessage(rawMsg *ssqs.Message) ([]byte, error) {
+// verifyMessage returns the plaintext of the signed message and the key ID of
+// its signer.
+func (e *Engine) verifyMessage(rawMsg *ssqs.Message) ([]byte, string, error) {
 	decoded, _ := clearsign.Decode([]byte(rawMsg.Body))
 	if decoded == nil {
-		return nil, &InvalidBlockError{rawMsg.ID}
+		return nil, "", &InvalidBlockError{rawMsg.ID}
 	}
-	if _, err := openpgp.CheckDetachedSignature(e.keyring, bytes.NewReader(decoded.Bytes), decoded.ArmoredSignature.Body); err != nil {
-		return nil, err
+	signer, err := openpgp.CheckDetachedSignature(e.keyring, bytes.NewReader(decoded.Bytes), decoded.ArmoredSignature.Body)
+	if err != nil {
+		return nil, "", err
 	}
-	return decoded.Plaintext, nil
+	return decoded.Plaintext, signer.PrimaryKey.KeyIdString(), nil
 }
@@ -343,7 +343,11 @@ func TestStart(t *testing.T) {
 					So(e, ShouldNotBeNil)
 					So(err, ShouldBeNil)
 
-					hfunction := func(ctx context.Context, msg *Message) error { return nil }
+					signers := make(chan string, 1)
+					hfunction := func(ctx context.Context, msg *Message) error {
+						signers <- msg.Signer
+						return nil
+					}
 					e.handlers = map[string]HandlerFunc{"test": hfunction}
 					ErrHandler = defaultErrHandler
 
@@ -353,6 +357,30 @@ func TestStart(t *testing.T) {
 					pMessage := producer.message
 					producer.mu.Unlock()
 					So(pMessage, ShouldEqual, `{"ID":"200","Success":true}`)
+					So(<-signers, ShouldEqual, e.keyring[0].PrimaryKey.KeyIdString())
+				})
+			})
+
+			Convey("handler results are propogated as expected", func() {
+				withMocks(false, validMessage, func(producer *mockProducer) {
+					e, err := New(ctx, &config.Configuration{ConsumerQueue: "foo", ConsumerQueueURL: "bar", ProducerQueue: "baz", AWSRegion: "qux", VerificationKey: publicKey}, nil)
+					So(e, ShouldNotBeNil)
+					So(err, ShouldBeNil)
+
+					hfunction := func(ctx context.Context, msg *Message) error {
+						msg.Result = map[string]string{"foo": "bar"}
+						return nil
+					}
+					e.handlers = map[string]HandlerFunc{"test": hfunction}
+					ErrHandler = defaultErrHandler
+
+					ctx, cancel := context.WithCancel(context.Background())
+					go time.AfterFunc(time.Second*1, cancel)
+					e.Start(ctx)
+					producer.mu.Lock()
+					pMessage := producer.message
+					producer.mu.Unlock()
+					So(pMessage, ShouldEqual, `{"ID":"200","Result":{"foo":"bar"},"Success":true}`)
 				})
 			})
 		})
@@ -0,0 +1,78 @@
+package deployment
+
+import (
+	"context"
+	"time"
+
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/hashicorp/nomad/api"
+)
+
+// awaitingPromotion reports whether any task group in the deployment has
+// canaries that have not yet been promoted.
+func awaitingPromotion(deployment *api.Deployment) bool {
+	for _, tg := range deployment.TaskGroups {
+		if tg.DesiredCanaries > 0 && !tg.Promoted {
+			return true
+		}
+	}
+	return false
+}
+
+// canaryHealth reports whether all unpromoted canaries are healthy and whether
+// any of them are unhealthy.
+func canaryHealth(deployment *api.Deployment) (healthy, unhealthy bool) {
+	healthy = true
+	for _, tg := range deployment.TaskGroups {
+		if tg.DesiredCanaries == 0 || tg.Promoted {
+			continue
+		}
+		if tg.UnhealthyAllocs > 0 {
+			unhealthy = true
+		}
+		if len(tg.PlacedCanaries) < tg.DesiredCanaries || tg.HealthyAllocs < tg.DesiredCanaries {
+			healthy = false
+		}
+	}
+	return healthy, unhealthy
+}
+
+// promoteCanaries promotes the deployment once its canaries have been healthy
+// for the verification period. If a canary becomes unhealthy the deployment is
+// failed instead. healthySince tracks when the canaries were first seen healthy.
+func (d *Deployment) promoteCanaries(ctx context.Context, correlationID, evaluationID string, deployment *api.Deployment, healthySince *time.Time) error {
+	logData := log.Data{"evaluation": evaluationID, "job": deployment.JobID, "deployment": deployment.ID}
+
+	healthy, unhealthy := canaryHealth(deployment)
+	if unhealthy {
+		log.Warn(ctx, "canary allocations unhealthy - failing deployment", logData)
+		d.failDeployment(ctx, deployment.ID)
+		return &AbortedError{EvaluationID: evaluationID, CorrelationID: correlationID}
+	}
+	if !healthy {
+		*healthySince = time.Time{}
+		log.Info(ctx, "canaries not yet healthy - will re-test", logData)
+		return nil
+	}
+	if healthySince.IsZero() {
+		*healthySince = time.Now()
+	}
+	if time.Since(*healthySince) < d.canaryVerifyPeriod {
+		log.Info(ctx, "verifying healthy canaries - will re-test", logData)
+		return nil
+	}
+
+	if err := d.nomadClient.PromoteDeployment(ctx, deployment.ID); err != nil {
+		return err
+	}
+	log.Info(ctx, "canaries promoted", logData)
+	return nil
+}
+
+// failDeployment marks the deployment as failed so that Nomad stops placing
+// allocations for it. Errors are logged as the deployment has already failed.
+func (d *Deployment) failDeployment(ctx context.Context, deploymentID string) {
+	if err := d.nomadClient.FailDeployment(ctx, deploymentID); err != nil {
+		log.Error(ctx, "Deployment-failDeployment, d.nomadClient.FailDeployment() error", err, log.Data{"deployment": deploymentID})
+	}
+}
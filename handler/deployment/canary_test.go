@@ -0,0 +1,103 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	canaryUnpromoted = `{"JobSpecModifyIndex": 99, "ID": "d1", "JobID": "test", "Status": "running", "TaskGroups": {"web": {"DesiredCanaries": 1, "DesiredTotal": 2, "PlacedCanaries": ["54321"], "HealthyAllocs": 1, "Promoted": false}}}`
+	canaryPending    = `{"JobSpecModifyIndex": 99, "ID": "d1", "JobID": "test", "Status": "running", "TaskGroups": {"web": {"DesiredCanaries": 1, "DesiredTotal": 2, "PlacedCanaries": ["54321"], "HealthyAllocs": 0, "Promoted": false}}}`
+	canaryUnhealthy  = `{"JobSpecModifyIndex": 99, "ID": "d1", "JobID": "test", "Status": "running", "TaskGroups": {"web": {"DesiredCanaries": 1, "DesiredTotal": 2, "PlacedCanaries": ["54321"], "UnhealthyAllocs": 1, "Promoted": false}}}`
+
+	deploymentCanaryUnpromoted = `[` + otherDeployment + `,` + canaryUnpromoted + `]`
+	deploymentCanaryPending    = `[` + otherDeployment + `,` + canaryPending + `]`
+	deploymentCanaryUnhealthy  = `[` + otherDeployment + `,` + canaryUnhealthy + `]`
+)
+
+func TestCanaries(t *testing.T) {
+	withMocks(func() {
+		Convey("canary deployments function as expected", t, func() {
+			ctx := context.Background()
+			promoted, failed := 0, 0
+			httpmock.RegisterResponder("POST", fmt.Sprintf(promoteURL, nomadURL, "d1"), func(req *http.Request) (*http.Response, error) {
+				promoted++
+				return httpmock.NewStringResponse(200, `{}`), nil
+			})
+			httpmock.RegisterResponder("POST", fmt.Sprintf(failURL, nomadURL, "d1"), func(req *http.Request) (*http.Response, error) {
+				failed++
+				return httpmock.NewStringResponse(200, `{}`), nil
+			})
+
+			Convey("healthy canaries are promoted", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, "test"), func(req *http.Request) (*http.Response, error) {
+					if promoted > 0 {
+						return httpmock.NewStringResponse(200, deploymentSuccess), nil
+					}
+					return httpmock.NewStringResponse(200, deploymentCanaryUnpromoted), nil
+				})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.successCheckByDeployment(ctx, "1", "12345", "test", 99)
+				So(err, ShouldBeNil)
+				So(promoted, ShouldEqual, 1)
+				So(failed, ShouldEqual, 0)
+			})
+
+			Convey("canaries are only promoted once verified", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, "test"), func(req *http.Request) (*http.Response, error) {
+					if promoted > 0 {
+						return httpmock.NewStringResponse(200, deploymentSuccess), nil
+					}
+					return httpmock.NewStringResponse(200, deploymentCanaryUnpromoted), nil
+				})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient, canaryVerifyPeriod: time.Second * 2}
+				start := time.Now()
+				err := dep.successCheckByDeployment(ctx, "1", "12345", "test", 99)
+				So(err, ShouldBeNil)
+				So(promoted, ShouldEqual, 1)
+				So(time.Since(start), ShouldBeGreaterThanOrEqualTo, time.Second*3)
+			})
+
+			Convey("unhealthy canaries fail the deployment", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, "test"), httpmock.NewStringResponder(200, deploymentCanaryUnhealthy))
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.successCheckByDeployment(ctx, "1", "12345", "test", 99)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
+				So(promoted, ShouldEqual, 0)
+				So(failed, ShouldEqual, 1)
+			})
+
+			Convey("canaries that never become healthy fail the deployment on timeout", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, "test"), httpmock.NewStringResponder(200, deploymentCanaryPending))
+				dep := &Deployment{timeout: shortTimeout, nomadClient: nomadClient}
+				err := dep.successCheckByDeployment(ctx, "1", "12345", "test", 99)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "timed out waiting for action to complete")
+				So(promoted, ShouldEqual, 0)
+				So(failed, ShouldEqual, 1)
+			})
+
+			Convey("canaries awaiting promotion fail the deployment when the message is cancelled", func() {
+				ctx, cancel := context.WithCancelCause(ctx)
+				defer cancel(nil)
+				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, "test"), func(req *http.Request) (*http.Response, error) {
+					cancel(&CancelledError{MessageID: "2"})
+					return httpmock.NewStringResponse(200, deploymentCanaryPending), nil
+				})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.successCheckByDeployment(ctx, "1", "12345", "test", 99)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
+				So(promoted, ShouldEqual, 0)
+				So(failed, ShouldEqual, 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,177 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/message"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// CancelResult represents the result of cancelling in-flight deployments.
+type CancelResult struct {
+	Cancelled   []string
+	Deployments []string `json:",omitempty"`
+}
+
+// inflight tracks the messages being handled so that they can be cancelled.
+type inflight struct {
+	mu       sync.Mutex
+	messages map[string]*handling
+}
+
+// handling represents an in-flight message, with the targeted deployment its
+// jobs are registered by.
+type handling struct {
+	d        *Deployment
+	services []string
+	cancel   context.CancelCauseFunc
+}
+
+func newInflight() *inflight {
+	return &inflight{messages: make(map[string]*handling)}
+}
+
+// track registers the message as in flight until the returned func is called.
+// The returned context is cancelled if the message is.
+func (d *Deployment) track(ctx context.Context, id string, services ...string) (context.Context, func()) {
+	if d.inflight == nil || len(id) == 0 {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	d.inflight.mu.Lock()
+	d.inflight.messages[id] = &handling{d: d, services: services, cancel: cancel}
+	d.inflight.mu.Unlock()
+
+	return ctx, func() {
+		d.inflight.mu.Lock()
+		delete(d.inflight.messages, id)
+		d.inflight.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// cancelled returns the CancelledError the message was cancelled with, or else
+// the error it was handled with. A RollbackError is kept so that the restored
+// version of a cancelled deployment is reported.
+func cancelled(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	var re *RollbackError
+	if errors.As(err, &re) {
+		return err
+	}
+	var ce *CancelledError
+	if errors.As(context.Cause(ctx), &ce) {
+		return ce
+	}
+	return err
+}
+
+// isCancelled returns whether the context was cancelled by a cancel message,
+// rather than by the deployer shutting down.
+func isCancelled(ctx context.Context) bool {
+	var ce *CancelledError
+	return errors.As(context.Cause(ctx), &ce)
+}
+
+// CancelHandler handles cancel messages that are delegated by the engine. The
+// in-flight message with the given ID, or else every one deploying the service,
+// is cancelled.
+// TODO This function will be removed once the new queue has been implemented
+func (d *Deployment) CancelHandler(ctx context.Context, msg *engine.Message) error {
+	res, err := d.cancel(ctx, msg.ID, msg.MessageID, msg.Service)
+	if err != nil {
+		log.Error(ctx, "Deployment-CancelHandler, d.cancel() error", err, log.Data{"message": msg.MessageID, "service": msg.Service})
+		return err
+	}
+	msg.Result = res
+	return nil
+}
+
+func (d *Deployment) cancelNew(ctx context.Context, msg *message.MessageSQS) error {
+	res, err := d.cancel(ctx, msg.ID, msg.MessageID, msg.Job)
+	if err != nil {
+		log.Error(ctx, "Deployment-cancelNew, d.cancel() error", err, log.Data{"message": msg.MessageID, "service": msg.Job})
+		return err
+	}
+	msg.Result = res
+	return nil
+}
+
+// cancel fails the running Nomad deployments of the matching in-flight
+// messages, so Nomad reverts them, then cancels their contexts so they stop
+// monitoring their deployments. Deployments are failed first so that a rollback
+// started by a cancelled message is not failed as well.
+func (d *Deployment) cancel(ctx context.Context, cancelID, messageID, service string) (*CancelResult, error) {
+	if d.inflight == nil {
+		return nil, &CancelError{MessageID: messageID, Service: service}
+	}
+
+	d.inflight.mu.Lock()
+	var matched []*handling
+	res := &CancelResult{}
+	for id, h := range d.inflight.messages {
+		if id == cancelID || !h.matches(id, messageID, service) {
+			continue
+		}
+		matched = append(matched, h)
+		res.Cancelled = append(res.Cancelled, id)
+	}
+	d.inflight.mu.Unlock()
+
+	if len(matched) == 0 {
+		return nil, &CancelError{MessageID: messageID, Service: service}
+	}
+	sort.Strings(res.Cancelled)
+
+	for _, h := range matched {
+		for _, service := range h.services {
+			res.Deployments = append(res.Deployments, h.d.failRunning(ctx, service)...)
+		}
+		h.cancel(&CancelledError{MessageID: cancelID})
+	}
+	log.Info(ctx, "cancelled in-flight messages", log.Data{"messages": res.Cancelled})
+	return res, nil
+}
+
+// matches returns whether the in-flight message has the message ID, if one is
+// given, or else deploys the service.
+func (h *handling) matches(id, messageID, service string) bool {
+	if len(messageID) > 0 {
+		return id == messageID
+	}
+	for _, s := range h.services {
+		if len(service) > 0 && s == service {
+			return true
+		}
+	}
+	return false
+}
+
+// failRunning fails the job's running deployments and returns their IDs.
+func (d *Deployment) failRunning(ctx context.Context, jobID string) []string {
+	deployments, err := d.nomadClient.ListDeployments(ctx, jobID)
+	if err != nil {
+		log.Error(ctx, "Deployment-failRunning, d.nomadClient.ListDeployments() error", err, log.Data{"job": jobID})
+		return nil
+	}
+	var failed []string
+	for _, deployment := range deployments {
+		if deployment.Status != structs.DeploymentStatusRunning {
+			continue
+		}
+		if err := d.nomadClient.FailDeployment(ctx, deployment.ID); err != nil {
+			log.Error(ctx, "Deployment-failRunning, d.nomadClient.FailDeployment() error", err, log.Data{"deployment": deployment.ID})
+			continue
+		}
+		failed = append(failed, deployment.ID)
+	}
+	return failed
+}
@@ -0,0 +1,66 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCancel(t *testing.T) {
+	withMocks(func() {
+		Convey("in-flight deployments are cancelled", t, func() {
+			ctx := context.Background()
+			failed := 0
+			httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, "test"), httpmock.NewStringResponder(200, deploymentRunning))
+			httpmock.RegisterResponder("POST", fmt.Sprintf(failURL, nomadURL, "54321"), func(req *http.Request) (*http.Response, error) {
+				failed++
+				return httpmock.NewStringResponse(200, `{}`), nil
+			})
+
+			dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient, inflight: newInflight()}
+			monitor := func(id string) chan error {
+				errs := make(chan error, 1)
+				ctx, done := dep.track(ctx, id, "test")
+				go func() {
+					err := dep.successCheckByDeployment(ctx, id, "12345", "test", 99)
+					errs <- cancelled(ctx, err)
+					done()
+				}()
+				return errs
+			}
+
+			Convey("by the service they deploy", func() {
+				errs := monitor("1")
+				res, err := dep.cancel(ctx, "2", "", "test")
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, &CancelResult{Cancelled: []string{"1"}, Deployments: []string{"54321"}})
+				So(<-errs, ShouldResemble, &CancelledError{MessageID: "2"})
+				So(failed, ShouldEqual, 1)
+			})
+
+			Convey("by their message ID", func() {
+				errs, other := monitor("1"), monitor("3")
+				res, err := dep.cancel(ctx, "2", "3", "")
+				So(err, ShouldBeNil)
+				So(res.Cancelled, ShouldResemble, []string{"3"})
+				So(<-other, ShouldResemble, &CancelledError{MessageID: "2"})
+
+				_, err = dep.cancel(ctx, "4", "1", "")
+				So(err, ShouldBeNil)
+				So((<-errs).Error(), ShouldEqual, "cancelled")
+			})
+
+			Convey("unless none match", func() {
+				_, err := dep.cancel(ctx, "2", "", "test")
+				So(err, ShouldResemble, &CancelError{Service: "test"})
+				_, err = (&Deployment{}).cancel(ctx, "2", "1", "")
+				So(err, ShouldResemble, &CancelError{MessageID: "1"})
+				So(failed, ShouldEqual, 0)
+			})
+		})
+	})
+}
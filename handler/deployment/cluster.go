@@ -0,0 +1,67 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ONSdigital/dp-deployer/config"
+	"github.com/ONSdigital/dp-deployer/nomadclient"
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	nomad "github.com/ONSdigital/dp-nomad"
+)
+
+// DefaultCluster is the name of the cluster at NOMAD_ENDPOINT, which messages
+// without a cluster are deployed to.
+const DefaultCluster = ""
+
+// Cluster represents a Nomad cluster that deployments can be routed to.
+type Cluster struct {
+	API      nomadclient.Client
+	Client   *nomad.Client
+	Endpoint string
+	Token    nomadclient.TokenSource
+	Tokens   *nomadclient.VaultTokens
+	Watcher  *Watcher
+}
+
+// NewCluster returns a cluster that jobs are deployed to and health checked with
+// the API client, watching its event stream with the Nomad client if enabled.
+// The API client must already authorise its requests with the token source.
+func NewCluster(cfg *config.Configuration, endpoint string, token nomadclient.TokenSource, nomadClient *nomad.Client, apiClient nomadclient.Client) *Cluster {
+	c := &Cluster{API: apiClient, Client: nomadClient, Endpoint: endpoint, Token: token}
+	if cfg.NomadEventStream {
+		c.Watcher = NewWatcher(nomadClient, endpoint, token, cfg.NomadRegion)
+	}
+	return c
+}
+
+// Checker checks the health of the cluster's Nomad agent.
+func (c *Cluster) Checker(ctx context.Context, state *health.CheckState) error {
+	if err := c.API.Health(ctx); err != nil {
+		code := 0
+		var cre *nomadclient.ResponseError
+		if errors.As(err, &cre) {
+			code = cre.StatusCode
+		}
+		state.Update(health.StatusCritical, nomad.ServiceName+nomad.StatusMessage[health.StatusCritical], code)
+		return err
+	}
+	return state.Update(health.StatusOK, nomad.ServiceName+nomad.StatusMessage[health.StatusOK], 200)
+}
+
+// cluster returns a copy of the deployment that makes its requests to the named
+// cluster.
+func (d *Deployment) cluster(name string) (*Deployment, error) {
+	if name == DefaultCluster {
+		return d, nil
+	}
+	c, ok := d.clusters[name]
+	if !ok {
+		return nil, &ClusterError{Cluster: name}
+	}
+	t := *d
+	t.nomadClient = c.API.Target(d.namespace, d.region)
+	t.watcher = c.Watcher
+	t.tokens = c.Tokens
+	return &t, nil
+}
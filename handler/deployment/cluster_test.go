@@ -0,0 +1,94 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/config"
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/nomadclient"
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	dpnethttp "github.com/ONSdigital/dp-net/v2/http"
+	nomad "github.com/ONSdigital/dp-nomad"
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCluster(t *testing.T) {
+	Convey("clusters are created as expected", t, func() {
+		apiClient := nomadclient.New("https://nomad.dr:4646", "token", nil, 0, 0)
+		c := NewCluster(&config.Configuration{NomadEventStream: true, NomadRegion: "eu"}, "https://nomad.dr:4646", nomadclient.StaticToken("token"), &nomad.Client{}, apiClient)
+		So(c.API, ShouldEqual, apiClient)
+		So(c.Endpoint, ShouldEqual, "https://nomad.dr:4646")
+		So(c.Token, ShouldEqual, nomadclient.StaticToken("token"))
+		So(c.Watcher, ShouldNotBeNil)
+		So(c.Watcher.region, ShouldEqual, "eu")
+
+		c = NewCluster(&config.Configuration{}, "https://nomad.dr:4646", nomadclient.StaticToken("token"), &nomad.Client{}, apiClient)
+		So(c.Watcher, ShouldBeNil)
+	})
+
+	Convey("messages are routed to their cluster", t, func() {
+		defaultClient := nomadclient.New(nomadURL, "default", nil, 0, 0)
+		drClient := nomadclient.New("https://nomad.dr:4646", "dr", nil, 0, 0)
+		dep := &Deployment{nomadClient: defaultClient, namespace: "dp", clusters: map[string]*Cluster{"dr": {API: drClient, Endpoint: "https://nomad.dr:4646", Token: nomadclient.StaticToken("dr")}}}
+
+		d, err := dep.cluster(DefaultCluster)
+		So(err, ShouldBeNil)
+		So(d, ShouldEqual, dep)
+
+		d, err = dep.cluster("dr")
+		So(err, ShouldBeNil)
+		So(d.nomadClient, ShouldResemble, drClient.Target("dp", ""))
+		So(dep.nomadClient, ShouldEqual, defaultClient)
+
+		_, err = dep.cluster("unknown")
+		So(err, ShouldResemble, &ClusterError{Cluster: "unknown"})
+	})
+}
+
+func TestClusterChecker(t *testing.T) {
+	withMocks(func() {
+		Convey("clusters are health checked with their token source", t, func() {
+			var token string
+			httpmock.RegisterResponder("GET", nomadURL+"/v1/agent/health?type=client", func(req *http.Request) (*http.Response, error) {
+				token = req.Header.Get("X-Nomad-Token")
+				return httpmock.NewStringResponse(200, `{}`), nil
+			})
+			c := &Cluster{API: nomadclient.New(nomadURL, "", dpnethttp.DefaultClient, 0, 0).WithToken(nomadclient.StaticToken("vault"))}
+
+			state := health.NewCheckState("Nomad")
+			So(c.Checker(context.Background(), state), ShouldBeNil)
+			So(state.Status(), ShouldEqual, health.StatusOK)
+			So(token, ShouldEqual, "vault")
+
+			httpmock.RegisterResponder("GET", nomadURL+"/v1/agent/health?type=client", httpmock.NewStringResponder(403, "Permission denied"))
+			So(c.Checker(context.Background(), state), ShouldNotBeNil)
+			So(state.Status(), ShouldEqual, health.StatusCritical)
+			So(state.StatusCode(), ShouldEqual, 403)
+		})
+	})
+}
+
+func TestClusterHandler(t *testing.T) {
+	withMocks(func() {
+		Convey("deployments to unknown clusters are rejected", t, func() {
+			dep := &Deployment{root: t.TempDir(), nomadClient: nomadClient}
+			err := dep.Handler(context.Background(), &engine.Message{ID: "1", Service: "test", Cluster: "unknown"})
+			So(err, ShouldResemble, &ClusterError{Cluster: "unknown"})
+			So(httpmock.GetTotalCallCount(), ShouldEqual, 0)
+		})
+
+		Convey("plans are made on the message's cluster", t, func() {
+			drURL := "https://nomad.dr:4646"
+			httpmock.RegisterResponder("POST", fmt.Sprintf(planURL, drURL, "test"), httpmock.NewStringResponder(200, planSuccess))
+			dep := &Deployment{nomadClient: nomadClient, clusters: map[string]*Cluster{"dr": {API: nomadclient.New(drURL, "", dpnethttp.DefaultClient, 0, 0), Endpoint: drURL}}}
+			d, err := dep.cluster("dr")
+			So(err, ShouldBeNil)
+			So(d.plan(context.Background(), &engine.Message{ID: "1", Service: "test"}), ShouldBeNil)
+			So(httpmock.GetCallCountInfo()["POST "+fmt.Sprintf(planURL, drURL, "test")], ShouldEqual, 1)
+		})
+	})
+}
@@ -2,38 +2,27 @@
 package deployment
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/ONSdigital/dp-deployer/config"
 	"github.com/ONSdigital/dp-deployer/engine"
 	"github.com/ONSdigital/dp-deployer/message"
 	job "github.com/ONSdigital/dp-deployer/nomad"
+	"github.com/ONSdigital/dp-deployer/nomadclient"
 	"github.com/ONSdigital/dp-deployer/s3"
-	nomad "github.com/ONSdigital/dp-nomad"
 	"github.com/ONSdigital/log.go/v2/log"
 	"github.com/hashicorp/nomad/api"
-	"github.com/hashicorp/nomad/jobspec"
 	"github.com/hashicorp/nomad/nomad/structs"
-	"github.com/slimsag/untargz"
 )
 
-const (
-	infoURL        = "%s/v1/job/%s"
-	deploymentURL  = "%s/v1/job/%s/deployments"
-	allocationsURL = "%s/v1/job/%s/allocations"
-	planURL        = "%s/v1/job/%s/plan"
-	runURL         = "%s/v1/jobs"
-)
-
-var jsonFrom func(string) ([]byte, error)
+var jsonFrom func(d *Deployment, ctx context.Context, jobPath string, vars map[string]string) ([]byte, error)
 
 type payload struct {
 	Job *api.Job
@@ -41,45 +30,106 @@ type payload struct {
 
 // Deployment represents a deployment.
 type Deployment struct {
-	s3Client    s3.Client
-	nomadClient *nomad.Client
-	root        string
-	endpoint    string
-	token       string
-	timeout     time.Duration
+	s3Client             s3.Client
+	nomadClient          nomadclient.Client
+	root                 string
+	timeout              time.Duration
+	rollbackPolicy       string
+	canaryVerifyPeriod   time.Duration
+	policy               PlanPolicy
+	environment          string
+	variablesConfig      map[string]string
+	ecrURL               string
+	deploymentsBucket    string
+	requireDigests       bool
+	bundleMaxSize        int64
+	bundleMaxFileSize    int64
+	keepFailedWorkspaces bool
+	jobFailurePolicy     string
+	watcher              *Watcher
+	diagnosticLogLines   int
+	namespace            string
+	region               string
+	clusters             map[string]*Cluster
+	tokens               *nomadclient.VaultTokens
+	tokenRole            string
+	namespaceRoles       map[string]string
+	signerRoles          map[string]string
+	inflight             *inflight
+	protectedJobs        []string
+	scaleMin             int
+	scaleMax             int
+	restartBatchSize     int
+	restartBatchPause    time.Duration
 }
 
-// New returns a new deployment.
-func New(cfg *config.Configuration, deploymentsClient s3.Client, nomadClient *nomad.Client) *Deployment {
+// New returns a new deployment to the clusters, which must include the
+// DefaultCluster.
+func New(cfg *config.Configuration, deploymentsClient s3.Client, clusters map[string]*Cluster) *Deployment {
+	c := clusters[DefaultCluster]
 
 	if jsonFrom == nil {
-		jsonFrom = jsonFromFile
+		jsonFrom = (*Deployment).jsonFromFile
 	}
 
+	nomadClient := c.API.Target(cfg.NomadNamespace, cfg.NomadRegion)
+
 	return &Deployment{
-		s3Client:    deploymentsClient,
-		nomadClient: nomadClient,
-		root:        cfg.DeploymentRoot,
-		endpoint:    cfg.NomadEndpoint,
-		token:       cfg.NomadToken,
-		timeout:     cfg.DeploymentTimeout,
+		s3Client:             deploymentsClient,
+		nomadClient:          nomadClient,
+		root:                 cfg.DeploymentRoot,
+		timeout:              cfg.DeploymentTimeout,
+		rollbackPolicy:       cfg.RollbackPolicy,
+		canaryVerifyPeriod:   cfg.CanaryVerifyPeriod,
+		policy:               NewPlanPolicy(cfg),
+		environment:          cfg.Environment,
+		variablesConfig:      cfg.JobVariables,
+		ecrURL:               cfg.ECR_URL,
+		deploymentsBucket:    cfg.DeploymentsBucketName,
+		requireDigests:       cfg.RequireArtifactDigests,
+		bundleMaxSize:        cfg.BundleMaxSize,
+		bundleMaxFileSize:    cfg.BundleMaxFileSize,
+		keepFailedWorkspaces: cfg.KeepFailedWorkspaces,
+		jobFailurePolicy:     cfg.JobFailurePolicy,
+		watcher:              c.Watcher,
+		diagnosticLogLines:   cfg.DiagnosticLogLines,
+		namespace:            cfg.NomadNamespace,
+		region:               cfg.NomadRegion,
+		clusters:             clusters,
+		tokens:               c.Tokens,
+		tokenRole:            cfg.NomadVaultRole,
+		namespaceRoles:       cfg.NomadVaultNamespaceRoles,
+		signerRoles:          cfg.NomadVaultSignerRoles,
+		inflight:             newInflight(),
+		protectedJobs:        cfg.ProtectedJobs,
+		scaleMin:             cfg.ScaleMinCount,
+		scaleMax:             cfg.ScaleMaxCount,
+		restartBatchSize:     cfg.RestartBatchSize,
+		restartBatchPause:    cfg.RestartBatchPause,
 	}
 }
 
 // Handler handles deployment messages that are delegated by the engine.
 // TODO This function will be removed once the new queue has been implemented
-func (d *Deployment) Handler(ctx context.Context, msg *engine.Message) error {
-	b, _, err := d.s3Client.Get(msg.Artifacts[0])
-	if err != nil {
-		log.Error(ctx, "Deployment-Handler, d.s3Client.Get() error", err)
+func (d *Deployment) Handler(ctx context.Context, msg *engine.Message) (err error) {
+	defer func() { d.cleanupWorkspace(ctx, msg, err) }()
+	return d.handle(ctx, "Handler", engineRequest(msg, messageServices(msg)...), func(ctx context.Context, d *Deployment) (interface{}, error) {
+		return nil, d.deploy(ctx, msg)
+	})
+}
+
+// deploy fetches the message's bundle and deploys its job, or each of its jobs.
+// TODO This function will be removed once the new queue has been implemented
+func (d *Deployment) deploy(ctx context.Context, msg *engine.Message) error {
+	if err := d.fetchBundle(ctx, msg); err != nil {
+		log.Error(ctx, "Deployment-Handler, d.fetchBundle() error", err)
 		return err
 	}
-	// Make sure to close the body when done with it for S3 GetObject APIs or
-	// will leak connections.
-	defer b.Close()
-
-	if err := untargz.Extract(b, fmt.Sprintf("%s/%s", d.root, msg.Service), nil); err != nil {
-		log.Error(ctx, "Deployment-Handler, untargz.Extract() error", err)
+	if len(msg.Jobs) > 0 {
+		return d.deployJobs(ctx, msg)
+	}
+	if err := d.substitute(ctx, msg); err != nil {
+		log.Error(ctx, "Deployment-Handler, d.substitute() error", err)
 		return err
 	}
 	if err := d.plan(ctx, msg); err != nil {
@@ -93,86 +143,163 @@ func (d *Deployment) Handler(ctx context.Context, msg *engine.Message) error {
 	return nil
 }
 
-// NewHandler change this to our way not using S3
-func (d *Deployment) NewHandler(ctx context.Context, cfg config.Configuration, msg *message.MessageSQS) error {
-	nomadJob := job.CreateJob(ctx, &cfg, msg.Job, msg)
-	if err := d.planNew(ctx, nomadJob); err != nil {
+// fetchBundle downloads the deployment bundles and extracts them to a new
+// workspace. A message with several artifacts has each one extracted to its own
+// directory in the workspace.
+// TODO This function will be removed once the new queue has been implemented
+func (d *Deployment) fetchBundle(ctx context.Context, msg *engine.Message) error {
+	w, err := d.newWorkspace(msg)
+	if err != nil {
+		log.Error(ctx, "Deployment-fetchBundle, d.newWorkspace() error", err)
+		return err
+	}
+	msg.Workspace = w.dir
+
+	if len(msg.Artifacts) == 1 {
+		return d.fetchArtifact(ctx, msg, msg.Artifacts[0], w)
+	}
+	for i, artifact := range msg.Artifacts {
+		aw, err := w.sub(strconv.Itoa(i))
+		if err != nil {
+			log.Error(ctx, "Deployment-fetchBundle, w.sub() error", err)
+			return err
+		}
+		if err := d.fetchArtifact(ctx, msg, artifact, aw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchArtifact downloads an artifact and extracts it to the workspace once
+// its digest has been verified against the message.
+func (d *Deployment) fetchArtifact(ctx context.Context, msg *engine.Message, artifact string, w *workspace) error {
+	expected, ok := msg.Digests[artifact]
+	if !ok && d.requireDigests {
+		err := &DigestError{Artifact: artifact}
+		log.Error(ctx, "Deployment-fetchArtifact, missing digest error", err, log.Data{"artifact": artifact})
 		return err
 	}
-	if err := d.runNew(ctx, nomadJob); err != nil {
+
+	b, _, err := d.s3Client.Get(artifact)
+	if err != nil {
+		log.Error(ctx, "Deployment-fetchArtifact, d.s3Client.Get() error", err)
+		return err
+	}
+	// Make sure to close the body when done with it for S3 GetObject APIs or
+	// will leak connections.
+	defer b.Close()
+
+	f, actual, err := w.spool(b)
+	if err != nil {
+		log.Error(ctx, "Deployment-fetchArtifact, w.spool() error", err, log.Data{"artifact": artifact})
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if !ok {
+		log.Warn(ctx, "bundle digest not verified", log.Data{"artifact": artifact})
+	} else if err := verifyDigest(artifact, expected, actual); err != nil {
+		log.Error(ctx, "Deployment-fetchArtifact, verifyDigest() error", err, log.Data{"artifact": artifact})
+		return err
+	}
+	if err := w.extract(f); err != nil {
+		log.Error(ctx, "Deployment-fetchArtifact, w.extract() error", err, log.Data{"artifact": artifact})
 		return err
 	}
 	return nil
 }
 
+// NewHandler change this to our way not using S3
+func (d *Deployment) NewHandler(ctx context.Context, cfg config.Configuration, msg *message.MessageSQS) error {
+	switch msg.Type {
+	case "cancel":
+		return d.cancelNew(ctx, msg)
+	case "dispatch":
+		return d.dispatchNew(ctx, msg)
+	case "restart":
+		return d.restartNew(ctx, msg)
+	case "scale":
+		return d.scaleNew(ctx, msg)
+	case "stop":
+		return d.stopNew(ctx, msg)
+	}
+	nomadJob := job.CreateJob(ctx, &cfg, msg.Job, msg)
+	if msg.Type == "plan" {
+		return d.handle(ctx, "NewHandler", sqsRequest(msg), func(ctx context.Context, d *Deployment) (interface{}, error) {
+			return result(d.planDiff(ctx, *nomadJob.Name, &nomadJob))
+		})
+	}
+	return d.handle(ctx, "NewHandler", sqsRequest(msg, *nomadJob.Name), func(ctx context.Context, d *Deployment) (interface{}, error) {
+		if err := d.planNew(ctx, nomadJob, msg.Override); err != nil {
+			return nil, err
+		}
+		return nil, d.runNew(ctx, msg, nomadJob)
+	})
+}
+
 // TODO This function will be removed once the new queue has been implemented
 func (d *Deployment) plan(ctx context.Context, msg *engine.Message) error {
 	log.Info(ctx, "planning job", log.Data{"msg": msg, "service": msg.Service})
 
-	var res api.JobPlanResponse
-	jFormat, err := d.jsonFormat(msg)
+	jFormat, err := d.jsonFormat(ctx, msg)
 	if err != nil {
 		log.Error(ctx, "Error formatting to json", err)
-	}
-
-	if err := d.post(fmt.Sprintf(planURL, d.endpoint, msg.Service), jFormat, &res); err != nil {
 		return err
 	}
-	if len(res.Warnings) == 0 && res.FailedTGAllocs == nil {
-		return nil
-	}
-	if len(res.Warnings) > 0 {
-		return &PlanError{Service: msg.Service, Warnings: res.Warnings}
+	var p payload
+	if err := json.Unmarshal(jFormat, &p); err != nil {
+		return err
 	}
-	j, err := json.Marshal(res.FailedTGAllocs)
+
+	res, err := d.planDiff(ctx, msg.Service, p.Job)
 	if err != nil {
 		return err
 	}
-	return &PlanError{Errors: string(j), Service: msg.Service}
+	return d.checkPlan(ctx, msg.Service, res, msg.Override)
 }
 
-func (d *Deployment) planNew(ctx context.Context, job api.Job) error {
+func (d *Deployment) planNew(ctx context.Context, job api.Job, override bool) error {
 	log.Info(ctx, "planning job", log.Data{"msg": job, "service": job.Name})
 
-	var res api.JobPlanResponse
-	if err := d.post(fmt.Sprintf(planURL, d.endpoint, *job.Name), job.Payload, &res); err != nil {
-		return err
-	}
-	if len(res.Warnings) == 0 && res.FailedTGAllocs == nil {
-		return nil
-	}
-	if len(res.Warnings) > 0 {
-		return &PlanError{Service: *job.Name, Warnings: res.Warnings}
-	}
-	j, err := json.Marshal(res.FailedTGAllocs)
+	res, err := d.planDiff(ctx, *job.Name, &job)
 	if err != nil {
 		return err
 	}
-	return &PlanError{Errors: string(j), Service: *job.Name}
+	return d.checkPlan(ctx, *job.Name, res, override)
 }
 
 // TODO This function will be removed once the new queue has been implemented
 func (d *Deployment) run(ctx context.Context, msg *engine.Message) error {
 	log.Info(ctx, "running job", log.Data{"msg": msg, "service": msg.Service})
 
-	var res api.JobRegisterResponse
-	jsonFormat, err := d.jsonFormat(msg)
+	jsonFormat, err := d.jsonFormat(ctx, msg)
 	if err != nil {
 		log.Error(ctx, "Error formatting to json", err)
+		return err
 	}
-	if err := d.post(fmt.Sprintf(runURL, d.endpoint), jsonFormat, &res); err != nil {
+	var p payload
+	if err := json.Unmarshal(jsonFormat, &p); err != nil {
 		return err
 	}
-	if err := d.deploymentSuccessCheck(ctx, msg.ID, res.EvalID, msg.Service, res.JobModifyIndex); err != nil {
+	res, err := d.nomadClient.RegisterJob(ctx, p.Job)
+	if err != nil {
 		return err
 	}
+	if err := d.deploymentSuccessCheck(ctx, msg.ID, res.EvalID, msg.Service, res.JobModifyIndex, msg.ForceRun); err != nil {
+		if diagnostics := d.diagnoseFailure(ctx, msg.Service, res.JobModifyIndex, err); diagnostics != nil {
+			msg.Result = diagnostics
+		}
+		return d.rollback(ctx, msg.ID, msg.Service, err)
+	}
 	return nil
 }
 
 // TODO This function will be removed once the new queue has been implemented
-func (d *Deployment) deploymentSuccessCheck(ctx context.Context, correlationID, evaluationID, jobID string, jobSpecModifyIndex uint64) error {
-	var jobInfo api.Job
-	if err := d.get(fmt.Sprintf(infoURL, d.endpoint, jobID), &jobInfo); err != nil {
+func (d *Deployment) deploymentSuccessCheck(ctx context.Context, correlationID, evaluationID, jobID string, jobSpecModifyIndex uint64, forceRun bool) error {
+	jobInfo, err := d.nomadClient.GetJob(ctx, jobID)
+	if err != nil {
 		return err
 	}
 
@@ -182,7 +309,7 @@ func (d *Deployment) deploymentSuccessCheck(ctx context.Context, correlationID,
 			return err
 		}
 	case api.JobTypeBatch:
-		if err := d.successCheckForBatchJobs(ctx, correlationID, evaluationID, jobID, *jobInfo.Version, jobInfo.Periodic != nil); err != nil {
+		if err := d.successCheckForBatchJobs(ctx, correlationID, evaluationID, jobID, *jobInfo.Version, jobInfo.Periodic != nil, forceRun); err != nil {
 			return err
 		}
 	default:
@@ -193,33 +320,36 @@ func (d *Deployment) deploymentSuccessCheck(ctx context.Context, correlationID,
 	return nil
 }
 
-func (d *Deployment) runNew(ctx context.Context, job api.Job) error {
+func (d *Deployment) runNew(ctx context.Context, msg *message.MessageSQS, job api.Job) error {
 	log.Info(ctx, "running job", log.Data{"msg": job, "service": job.Name})
 
-	var res api.JobRegisterResponse
-	if err := d.post(fmt.Sprintf(runURL, d.endpoint), job.Payload, &res); err != nil {
+	res, err := d.nomadClient.RegisterJob(ctx, &job)
+	if err != nil {
 		return err
 	}
 
 	switch *job.Type {
 	case api.JobTypeSystem:
-		if err := d.successCheckByAllocations(ctx, *job.Name, res.EvalID, *job.Name, *job.Version); err != nil {
-			return err
-		}
+		err = d.successCheckByAllocations(ctx, *job.Name, res.EvalID, *job.Name, *job.Version)
 	case api.JobTypeBatch:
-		if err := d.successCheckForBatchJobs(ctx, *job.Name, res.EvalID, *job.Name, *job.Version, job.Periodic != nil); err != nil {
-			return err
-		}
+		err = d.successCheckForBatchJobs(ctx, *job.Name, res.EvalID, *job.Name, *job.Version, job.Periodic != nil, msg.ForceRun)
 	default:
-		if err := d.successCheckByDeployment(ctx, *job.Name, res.EvalID, *job.Name, res.JobModifyIndex); err != nil {
-			return err
+		err = d.successCheckByDeployment(ctx, *job.Name, res.EvalID, *job.Name, res.JobModifyIndex)
+	}
+	if err != nil {
+		if diagnostics := d.diagnoseFailure(ctx, *job.Name, res.JobModifyIndex, err); diagnostics != nil {
+			msg.Result = diagnostics
 		}
+		return d.rollback(ctx, *job.Name, *job.Name, err)
 	}
 	return nil
 }
 
-func (d *Deployment) successCheckForBatchJobs(ctx context.Context, correlationID, evaluationID, jobID string, jobVersion uint64, periodic bool) error {
+func (d *Deployment) successCheckForBatchJobs(ctx context.Context, correlationID, evaluationID, jobID string, jobVersion uint64, periodic, forceRun bool) error {
 	minLogData := log.Data{"evaluation": evaluationID, "job": jobID, "job_version": jobVersion}
+	if periodic && forceRun {
+		return d.forceRun(ctx, correlationID, jobID)
+	}
 	if periodic {
 		log.Info(ctx, "deployment assumed successful - periodic batch jobs don't run immediately", minLogData)
 		return nil
@@ -230,10 +360,12 @@ func (d *Deployment) successCheckForBatchJobs(ctx context.Context, correlationID
 }
 
 func (d *Deployment) successCheckByAllocationsBatch(ctx context.Context, correlationID, evaluationID, jobID string, jobVersion uint64) error {
-	ticker := time.NewTicker(time.Second * 1)
-	defer ticker.Stop()
+	checks, stop := d.watch(jobID)
+	defer stop()
 	timeout := time.NewTimer(d.timeout)
 	minLogData := log.Data{"evaluation": evaluationID, "job": jobID, "job_version": jobVersion}
+	// jobInfo holds the reschedule policies once an allocation has failed
+	var jobInfo *api.Job
 
 	for {
 		select {
@@ -247,9 +379,9 @@ func (d *Deployment) successCheckByAllocationsBatch(ctx context.Context, correla
 			return &AbortedError{EvaluationID: evaluationID, CorrelationID: correlationID}
 		case <-timeout.C:
 			return &TimeoutError{Action: "deployment"}
-		case <-ticker.C:
-			var allocations []api.AllocationListStub
-			if err := d.get(fmt.Sprintf(allocationsURL, d.endpoint, jobID), &allocations); err != nil {
+		case <-checks:
+			allocations, err := d.nomadClient.ListAllocations(ctx, jobID)
+			if err != nil {
 				// Ensure timer is stopped and its resources are freed
 				if !timeout.Stop() {
 					// if the timer has been stopped then read from the channel
@@ -269,22 +401,52 @@ func (d *Deployment) successCheckByAllocationsBatch(ctx context.Context, correla
 			}
 
 			desiredStopIsRunning := false
-			var desiredAllocations []api.AllocationListStub
 			for _, allocation := range allocations {
-				if allocation.EvalID == evaluationID && allocation.DesiredStatus == structs.AllocDesiredStatusRun {
-					desiredAllocations = append(desiredAllocations, allocation)
-				} else if allocation.DesiredStatus != structs.AllocDesiredStatusRun &&
+				if allocation.DesiredStatus != structs.AllocDesiredStatusRun &&
 					allocation.ClientStatus == structs.AllocClientStatusRunning {
 					desiredStopIsRunning = true
 					break
 				}
 			}
+			var desiredAllocations []api.AllocationListStub
+			for _, allocation := range batchAllocations(allocations, evaluationID) {
+				// Rescheduled allocations are tracked through their replacement
+				if allocation.DesiredStatus == structs.AllocDesiredStatusRun && len(allocation.NextAllocation) == 0 {
+					desiredAllocations = append(desiredAllocations, allocation)
+				}
+			}
 
 			if !desiredStopIsRunning {
 				completedAllocations := 0
 				for _, allocation := range desiredAllocations {
-					if allocation.JobVersion == jobVersion && allocation.ClientStatus == structs.AllocClientStatusComplete {
+					if allocation.JobVersion != jobVersion {
+						continue
+					}
+					if allocation.ClientStatus == structs.AllocClientStatusComplete {
 						completedAllocations += 1
+						continue
+					}
+					if allocation.ClientStatus != structs.AllocClientStatusFailed && allocation.ClientStatus != structs.AllocClientStatusLost {
+						continue
+					}
+					if jobInfo == nil {
+						if jobInfo, err = d.nomadClient.GetJob(ctx, jobID); err != nil {
+							// Ensure timer is stopped and its resources are freed
+							if !timeout.Stop() {
+								// if the timer has been stopped then read from the channel
+								<-timeout.C
+							}
+							return err
+						}
+					}
+					if terminalFailure(allocation, jobInfo) {
+						// Ensure timer is stopped and its resources are freed
+						if !timeout.Stop() {
+							// if the timer has been stopped then read from the channel
+							<-timeout.C
+						}
+						log.Error(ctx, "batch deployment failed - allocation failed", errors.New("batch deployment failed - allocation failed"), minLogData)
+						return &AbortedError{EvaluationID: evaluationID, CorrelationID: correlationID}
 					}
 				}
 
@@ -304,11 +466,16 @@ func (d *Deployment) successCheckByAllocationsBatch(ctx context.Context, correla
 }
 
 func (d *Deployment) successCheckByDeployment(ctx context.Context, correlationID, evaluationID, jobID string, jobSpecModifyIndex uint64) error {
-	ticker := time.NewTicker(time.Second * 1)
-	defer ticker.Stop()
+	checks, stop := d.watch(jobID)
+	defer stop()
 	timeout := time.NewTimer(d.timeout)
 	minLogData := log.Data{"evaluation": evaluationID, "job": jobID, "job_modify_index": jobSpecModifyIndex}
 
+	// canaryDeploymentID is set while canaries await promotion, so the
+	// deployment can be failed if they are never promoted.
+	var canaryDeploymentID string
+	var canariesHealthySince time.Time
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -317,13 +484,21 @@ func (d *Deployment) successCheckByDeployment(ctx context.Context, correlationID
 				// if the timer has been stopped then read from the channel
 				<-timeout.C
 			}
+			if len(canaryDeploymentID) > 0 {
+				rctx, stop := rollbackContext(ctx)
+				d.failDeployment(rctx, canaryDeploymentID)
+				stop()
+			}
 			log.Error(ctx, "bailing on deployment status", errors.New("bailing on deployment status"), minLogData)
 			return &AbortedError{EvaluationID: evaluationID, CorrelationID: correlationID}
 		case <-timeout.C:
+			if len(canaryDeploymentID) > 0 {
+				d.failDeployment(ctx, canaryDeploymentID)
+			}
 			return &TimeoutError{Action: "deployment"}
-		case <-ticker.C:
-			var deployments []api.Deployment
-			if err := d.get(fmt.Sprintf(deploymentURL, d.endpoint, jobID), &deployments); err != nil {
+		case <-checks:
+			deployments, err := d.nomadClient.ListDeployments(ctx, jobID)
+			if err != nil {
 				// Ensure timer is stopped and its resources are freed
 				if !timeout.Stop() {
 					// if the timer has been stopped then read from the channel
@@ -364,6 +539,20 @@ func (d *Deployment) successCheckByDeployment(ctx context.Context, correlationID
 					}
 					log.Error(ctx, "deployment failed", errors.New("deployment failed"), logData)
 					return &AbortedError{EvaluationID: evaluationID, CorrelationID: correlationID}
+				case structs.DeploymentStatusRunning:
+					canaryDeploymentID = ""
+					if !awaitingPromotion(&deployment) {
+						break
+					}
+					canaryDeploymentID = deployment.ID
+					if err := d.promoteCanaries(ctx, correlationID, evaluationID, &deployment, &canariesHealthySince); err != nil {
+						// Ensure timer is stopped and its resources are freed
+						if !timeout.Stop() {
+							// if the timer has been stopped then read from the channel
+							<-timeout.C
+						}
+						return err
+					}
 				default:
 					log.Info(ctx, fmt.Sprintf("Unhandled deployment.Status: %s", deployment.Status))
 				}
@@ -380,8 +569,8 @@ func (d *Deployment) successCheckByDeployment(ctx context.Context, correlationID
 }
 
 func (d *Deployment) successCheckByAllocations(ctx context.Context, correlationID, evaluationID, jobID string, jobVersion uint64) error {
-	ticker := time.NewTicker(time.Second * 1)
-	defer ticker.Stop()
+	checks, stop := d.watch(jobID)
+	defer stop()
 	timeout := time.NewTimer(d.timeout)
 	minLogData := log.Data{"evaluation": evaluationID, "job": jobID, "job_version": jobVersion}
 
@@ -397,9 +586,9 @@ func (d *Deployment) successCheckByAllocations(ctx context.Context, correlationI
 			return &AbortedError{EvaluationID: evaluationID, CorrelationID: correlationID}
 		case <-timeout.C:
 			return &TimeoutError{Action: "deployment"}
-		case <-ticker.C:
-			var allocations []api.AllocationListStub
-			if err := d.get(fmt.Sprintf(allocationsURL, d.endpoint, jobID), &allocations); err != nil {
+		case <-checks:
+			allocations, err := d.nomadClient.ListAllocations(ctx, jobID)
+			if err != nil {
 				// Ensure timer is stopped and its resources are freed
 				if !timeout.Stop() {
 					// if the timer has been stopped then read from the channel
@@ -453,73 +642,33 @@ func (d *Deployment) successCheckByAllocations(ctx context.Context, correlationI
 	}
 }
 
-func (d *Deployment) get(url string, v interface{}) error {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return err
-	}
-
-	return d.doNomadReq(req, v)
-}
-
-func (d *Deployment) post(url string, reader []byte, v interface{}) error {
-	req, err := http.NewRequest("POST", url, bytes.NewReader(reader))
-	if err != nil {
-		return err
+// messageServices returns the services deployed by the message.
+func messageServices(msg *engine.Message) []string {
+	if len(msg.Jobs) == 0 {
+		return []string{msg.Service}
 	}
-	return d.doNomadReq(req, v)
-}
-
-func (d *Deployment) doNomadReq(req *http.Request, v interface{}) error {
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Nomad-Token", d.token)
-
-	res, err := d.nomadClient.Client.Do(context.Background(), req)
-	if err != nil {
-		return err
+	services := make([]string, len(msg.Jobs))
+	for i, job := range msg.Jobs {
+		services[i] = job.Name
 	}
-	return unmarshalAPIResponse(res, v)
+	return services
 }
 
-func unmarshalAPIResponse(r *http.Response, v interface{}) error {
-	defer r.Body.Close()
-
-	b, err := io.ReadAll(r.Body)
-	if err != nil {
-		return err
-	}
-	if r.StatusCode != http.StatusOK {
-		return &ClientResponseError{Body: string(b), StatusCode: r.StatusCode, URL: r.Request.URL.String()}
-	}
-	if err := json.Unmarshal(b, v); err != nil {
-		return err
-	}
-	return nil
+// jobPath returns the path of the job file in the message's workspace.
+func jobPath(msg *engine.Message) string {
+	return filepath.Join(msg.Workspace, msg.Service+".nomad")
 }
 
-func jsonFromFile(jobPath string) ([]byte, error) {
-	f, err := os.Open(jobPath)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	p, err := jobspec.Parse(f)
-	if err != nil {
-		return nil, err
-	}
-	j, err := json.Marshal(payload{p})
-	if err != nil {
-		return nil, err
-	}
-	return j, nil
-}
-
-func (d *Deployment) jsonFormat(msg *engine.Message) ([]byte, error) {
-	j, err := jsonFrom(fmt.Sprintf("%s/%s/%s.nomad", d.root, msg.Service, msg.Service))
-	if err != nil {
-		return nil, err
+// jsonFormat returns the message's job as JSON. The jobspec is parsed once and
+// kept on the message, so planning and running a job only parse it once.
+func (d *Deployment) jsonFormat(ctx context.Context, msg *engine.Message) ([]byte, error) {
+	if msg.JobSpec == nil {
+		j, err := jsonFrom(d, ctx, jobPath(msg), msg.Variables)
+		if err != nil {
+			return nil, err
+		}
+		msg.JobSpec = j
 	}
 
-	return j, nil
+	return d.targetJob(msg.JobSpec, msg.Datacenters)
 }
@@ -12,14 +12,34 @@ import (
 
 	"github.com/ONSdigital/dp-deployer/config"
 	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/message"
+	"github.com/ONSdigital/dp-deployer/nomadclient"
 	"github.com/ONSdigital/dp-deployer/s3"
 	dpnethttp "github.com/ONSdigital/dp-net/v2/http"
-	nomad "github.com/ONSdigital/dp-nomad"
 	"github.com/jarcoal/httpmock"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
-const nomadURL = "http://localhost:4646"
+const (
+	nomadURL      = "http://localhost:4646"
+	infoURL       = "%s/v1/job/%s"
+	planURL       = "%s/v1/job/%s/plan"
+	runURL        = "%s/v1/jobs"
+	jobsURL       = "%s/v1/jobs"
+	parseURL      = "%s/v1/jobs/parse"
+	versionsURL   = "%s/v1/job/%s/versions"
+	revertURL     = "%s/v1/job/%s/revert"
+	scaleURL      = "%s/v1/job/%s/scale"
+	forceURL      = "%s/v1/job/%s/periodic/force"
+	evaluationURL = "%s/v1/evaluation/%s"
+	dispatchURL   = "%s/v1/job/%s/dispatch"
+	promoteURL    = "%s/v1/deployment/promote/%s"
+	failURL       = "%s/v1/deployment/fail/%s"
+	allocationURL = "%s/v1/allocation/%s"
+	restartURL    = "%s/v1/client/allocation/%s/restart"
+	checksURL     = "%s/v1/client/allocation/%s/checks"
+	logsURL       = "%s/v1/client/fs/logs/%s?task=%s&type=stderr&origin=end&offset=%d&plain=true"
+)
 
 var (
 	jobSuccess             = `{"EvalID": "12345", "ID": "54321", "JobModifyIndex": 99}`
@@ -50,7 +70,7 @@ var (
 	planSuccess  = `{}`
 	planWarnings = `{"Warnings": "test warning"}`
 
-	nomadClient = &nomad.Client{}
+	nomadClient nomadclient.Client
 
 	normalTimeout = time.Second * 10
 	shortTimeout  = time.Second * 2
@@ -63,7 +83,7 @@ func TestNew(t *testing.T) {
 
 	withEnv(func() {
 		Convey("a deployment is returned", t, func() {
-			d := New(&config.Configuration{DeploymentRoot: "foo", NomadEndpoint: "https://", NomadToken: "baz", NomadCACert: "", NomadTLSSkipVerify: false, AWSRegion: "qux"}, &s3.ClientMock{}, nomadClient)
+			d := New(&config.Configuration{DeploymentRoot: "foo", NomadEndpoint: "https://", NomadToken: "baz", NomadCACert: "", NomadTLSSkipVerify: false, AWSRegion: "qux"}, &s3.ClientMock{}, map[string]*Cluster{DefaultCluster: {API: nomadclient.New("https://", "baz", nil, 0, 0), Endpoint: "https://", Token: nomadclient.StaticToken("baz")}})
 			So(d, ShouldNotBeNil)
 		})
 	})
@@ -78,7 +98,7 @@ func TestPlan(t *testing.T) {
 
 			Convey("api errors handled correctly", func() {
 				httpmock.RegisterResponder("POST", nomadURL+"/v1/job/test/plan", httpmock.NewStringResponder(500, "server error"))
-				dep := &Deployment{endpoint: nomadURL, nomadClient: nomadClient}
+				dep := &Deployment{nomadClient: nomadClient}
 				err := dep.plan(ctx, &engine.Message{Service: "test"})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "unexpected response from client")
@@ -86,7 +106,7 @@ func TestPlan(t *testing.T) {
 
 			Convey("plan warnings handled correctly", func() {
 				httpmock.RegisterResponder("POST", nomadURL+"/v1/job/test/plan", httpmock.NewStringResponder(200, planWarnings))
-				dep := &Deployment{endpoint: nomadURL, nomadClient: nomadClient}
+				dep := &Deployment{nomadClient: nomadClient}
 				err := dep.plan(ctx, &engine.Message{Service: "test"})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "plan for tasks generated errors or warnings")
@@ -94,7 +114,7 @@ func TestPlan(t *testing.T) {
 
 			Convey("plan allocation errors handled correctly", func() {
 				httpmock.RegisterResponder("POST", nomadURL+"/v1/job/test/plan", httpmock.NewStringResponder(200, planErrors))
-				dep := &Deployment{endpoint: nomadURL, nomadClient: nomadClient}
+				dep := &Deployment{nomadClient: nomadClient}
 				err := dep.plan(ctx, &engine.Message{Service: "test"})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "plan for tasks generated errors or warnings")
@@ -102,7 +122,7 @@ func TestPlan(t *testing.T) {
 
 			Convey("valid plans handled correctly", func() {
 				httpmock.RegisterResponder("POST", nomadURL+"/v1/job/test/plan", httpmock.NewStringResponder(200, planSuccess))
-				dep := &Deployment{endpoint: nomadURL, nomadClient: nomadClient}
+				dep := &Deployment{nomadClient: nomadClient}
 				err := dep.plan(ctx, &engine.Message{Service: "test"})
 				So(err, ShouldBeNil)
 			})
@@ -118,7 +138,7 @@ func TestRun(t *testing.T) {
 
 			Convey("job api errors handled correctly", func() {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(500, "server error"))
-				dep := &Deployment{endpoint: nomadURL, nomadClient: nomadClient}
+				dep := &Deployment{nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{Service: "test"})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "unexpected response from client")
@@ -129,7 +149,7 @@ func TestRun(t *testing.T) {
 				serviceName := "test"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(500, "server error"))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(500, "server error"))
-				dep := &Deployment{endpoint: nomadURL, nomadClient: nomadClient}
+				dep := &Deployment{nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{Service: serviceName})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "unexpected response from client")
@@ -141,7 +161,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, serviceJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, serviceName), httpmock.NewStringResponder(500, "server error"))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: serviceName})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "unexpected response from client")
@@ -153,7 +173,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, serviceJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, serviceName), httpmock.NewStringResponder(200, deploymentError))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: serviceName})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
@@ -165,7 +185,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, serviceJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, serviceName), httpmock.NewStringResponder(200, deploymentRunning))
-				dep := &Deployment{endpoint: nomadURL, timeout: shortTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: shortTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: serviceName})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "timed out waiting for action to complete")
@@ -178,7 +198,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, serviceJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, serviceName), httpmock.NewStringResponder(200, deploymentRunning))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: "test"})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
@@ -189,7 +209,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, serviceJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, serviceName), httpmock.NewStringResponder(200, deploymentSuccess))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: "test"})
 				So(err, ShouldBeNil)
 				cancel()
@@ -200,7 +220,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, systemJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(500, "server error"))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: serviceName})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "unexpected response from client")
@@ -212,7 +232,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, systemJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, emptyAllocations))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: serviceName})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
@@ -224,7 +244,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, systemJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, allocationsPending))
-				dep := &Deployment{endpoint: nomadURL, timeout: shortTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: shortTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: serviceName})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "timed out waiting for action to complete")
@@ -237,7 +257,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, systemJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, allocationsPending))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: "test"})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
@@ -249,7 +269,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, systemJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, allocationsError))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: "test"})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
@@ -261,7 +281,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, systemJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, allocationsOldVersion))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: "test"})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
@@ -273,7 +293,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, systemJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, allocationsStopIsRunning))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: "test"})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
@@ -284,7 +304,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, systemJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, allocationsSuccess))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: "test"})
 				So(err, ShouldBeNil)
 				cancel()
@@ -295,7 +315,7 @@ func TestRun(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, systemJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, allocationsStopIsStopped))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: "test"})
 				So(err, ShouldBeNil)
 				cancel()
@@ -316,7 +336,7 @@ func TestRun_Batch(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, batchJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(500, "server error"))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: serviceName})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "unexpected response from client")
@@ -328,7 +348,7 @@ func TestRun_Batch(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, batchJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, emptyAllocations))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: serviceName})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
@@ -340,7 +360,7 @@ func TestRun_Batch(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, batchJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, allocationsPending))
-				dep := &Deployment{endpoint: nomadURL, timeout: shortTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: shortTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54322", Service: serviceName})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "timed out waiting for action to complete")
@@ -353,7 +373,7 @@ func TestRun_Batch(t *testing.T) {
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, batchJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, allocationsPending))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: "test"})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
@@ -365,7 +385,7 @@ func TestRun_Batch(t *testing.T) {
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, batchJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, allocationsError))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54322", Service: "test"})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
@@ -377,7 +397,7 @@ func TestRun_Batch(t *testing.T) {
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, batchJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, allocationsStopIsRunning))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: "test"})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
@@ -388,7 +408,7 @@ func TestRun_Batch(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, batchJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, batchAllocationsSuccess))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: "test"})
 				So(err, ShouldBeNil)
 				cancel()
@@ -399,7 +419,7 @@ func TestRun_Batch(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, batchJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, serviceName), httpmock.NewStringResponder(200, allocationsStopIsStopped))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: "test"})
 				So(err, ShouldBeNil)
 				cancel()
@@ -409,7 +429,7 @@ func TestRun_Batch(t *testing.T) {
 				serviceName := "test"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, serviceName), httpmock.NewStringResponder(200, periodicJobInfoSuccess))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
 				err := dep.run(ctx, &engine.Message{ID: "54321", Service: "test"})
 				So(err, ShouldBeNil)
 				cancel()
@@ -431,8 +451,8 @@ func TestRunNew(t *testing.T) {
 			Convey("job api errors handled correctly", func() {
 				jobType := "service"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(500, "server error"))
-				dep := &Deployment{endpoint: nomadURL, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "unexpected response from client")
 				cancel()
@@ -442,8 +462,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "service"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, jobName), httpmock.NewStringResponder(500, "server error"))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "unexpected response from client")
 				cancel()
@@ -453,8 +473,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "service"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, jobName), httpmock.NewStringResponder(200, deploymentError))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
 				cancel()
@@ -464,8 +484,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "service"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, jobName), httpmock.NewStringResponder(200, deploymentRunning))
-				dep := &Deployment{endpoint: nomadURL, timeout: shortTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: shortTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "timed out waiting for action to complete")
 				cancel()
@@ -476,8 +496,8 @@ func TestRunNew(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, jobName), httpmock.NewStringResponder(200, deploymentRunning))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
 			})
@@ -486,8 +506,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "service"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, jobName), httpmock.NewStringResponder(200, deploymentSuccess))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldBeNil)
 				cancel()
 			})
@@ -496,8 +516,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "system"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(500, "server error"))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "unexpected response from client")
 				cancel()
@@ -507,8 +527,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "system"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, emptyAllocations))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
 				cancel()
@@ -518,8 +538,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "system"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsPending))
-				dep := &Deployment{endpoint: nomadURL, timeout: shortTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: shortTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "timed out waiting for action to complete")
 				cancel()
@@ -530,8 +550,8 @@ func TestRunNew(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsPending))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
 			})
@@ -541,8 +561,8 @@ func TestRunNew(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsError))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
 			})
@@ -552,8 +572,8 @@ func TestRunNew(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsOldVersion))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
 			})
@@ -563,8 +583,8 @@ func TestRunNew(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsStopIsRunning))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
 			})
@@ -573,8 +593,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "system"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsSuccess))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldBeNil)
 				cancel()
 			})
@@ -583,8 +603,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "system"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsStopIsStopped))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldBeNil)
 				cancel()
 			})
@@ -593,8 +613,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "batch"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(500, "server error"))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "unexpected response from client")
 				cancel()
@@ -604,8 +624,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "batch"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, emptyAllocations))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
 				cancel()
@@ -615,8 +635,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "batch"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsPending))
-				dep := &Deployment{endpoint: nomadURL, timeout: shortTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: shortTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "timed out waiting for action to complete")
 				cancel()
@@ -627,8 +647,8 @@ func TestRunNew(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsPending))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
 			})
@@ -636,10 +656,11 @@ func TestRunNew(t *testing.T) {
 			Convey("batch deployment failed allocation handled correctly", func() {
 				jobType := "batch"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, jobName), httpmock.NewStringResponder(200, batchJobInfoSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsError))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
 			})
@@ -649,8 +670,8 @@ func TestRunNew(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsOldVersion))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
 			})
@@ -660,8 +681,8 @@ func TestRunNew(t *testing.T) {
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsStopIsRunning))
 				time.AfterFunc(time.Second*2, cancel)
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
 			})
@@ -670,8 +691,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "batch"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsSuccess))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldBeNil)
 				cancel()
 			})
@@ -680,8 +701,8 @@ func TestRunNew(t *testing.T) {
 				jobType := "batch"
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
 				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, jobName), httpmock.NewStringResponder(200, allocationsStopIsStopped))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion})
 				So(err, ShouldBeNil)
 				cancel()
 			})
@@ -691,8 +712,8 @@ func TestRunNew(t *testing.T) {
 				trueVal := true
 				periodic := api.PeriodicConfig{Enabled: &trueVal}
 				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
-				dep := &Deployment{endpoint: nomadURL, timeout: normalTimeout, nomadClient: nomadClient}
-				err := dep.runNew(ctx, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion, Periodic: &periodic})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				err := dep.runNew(ctx, &message.MessageSQS{}, api.Job{ID: &jobID, Name: &jobName, Type: &jobType, Version: &jobVersion, Periodic: &periodic})
 				So(err, ShouldBeNil)
 				cancel()
 			})
@@ -716,10 +737,7 @@ func withMocks(f func()) {
 	myClient.HTTPClient = http.DefaultClient
 	myClient.MaxRetries = 1
 
-	nomadClient = &nomad.Client{
-		Client: myClient,
-		URL:    nomadURL,
-	}
+	nomadClient = nomadclient.New(nomadURL, "", myClient, 0, 0)
 
 	defaultJSONFrom := jsonFrom
 
@@ -727,6 +745,8 @@ func withMocks(f func()) {
 		jsonFrom = defaultJSONFrom
 	}()
 
-	jsonFrom = func(string) ([]byte, error) { return nil, nil }
+	jsonFrom = func(*Deployment, context.Context, string, map[string]string) ([]byte, error) {
+		return []byte(`{"Job": {}}`), nil
+	}
 	f()
 }
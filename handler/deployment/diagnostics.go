@@ -0,0 +1,222 @@
+package deployment
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// Limits that keep diagnostics small enough for a response message.
+const (
+	maxDiagnosedAllocations = 3
+	maxTaskEvents           = 5
+	maxLogBytes             = 16 << 10
+	maxLineLength           = 500
+)
+
+// Diagnostics summarises why a job version failed to deploy.
+type Diagnostics struct {
+	Job               string
+	JobVersion        uint64
+	DeploymentID      string                   `json:",omitempty"`
+	StatusDescription string                   `json:",omitempty"`
+	FailingChecks     []string                 `json:",omitempty"`
+	Allocations       []*AllocationDiagnostics `json:",omitempty"`
+}
+
+// AllocationDiagnostics summarises a failed allocation.
+type AllocationDiagnostics struct {
+	ID           string
+	Node         string
+	ClientStatus string
+	Tasks        []*TaskDiagnostics `json:",omitempty"`
+}
+
+// TaskDiagnostics summarises the state of an allocation's task, with its most
+// recent events and, if it failed, the end of its stderr.
+type TaskDiagnostics struct {
+	Task     string
+	State    string
+	Failed   bool
+	Restarts uint64
+	Events   []string `json:",omitempty"`
+	Stderr   []string `json:",omitempty"`
+}
+
+// diagnoseFailure returns diagnostics for deployments that failed or timed out.
+// Monitoring that was cancelled has nothing to diagnose.
+func (d *Deployment) diagnoseFailure(ctx context.Context, jobID string, jobModifyIndex uint64, cause error) *Diagnostics {
+	switch cause.(type) {
+	case *AbortedError, *TimeoutError:
+	default:
+		return nil
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return d.diagnose(ctx, jobID, jobModifyIndex)
+}
+
+// diagnose collects diagnostics from Nomad for the failed job version
+// registered at the job modify index, and logs them. Diagnostics are best
+// effort, so nil is returned if the job version can't be found.
+func (d *Deployment) diagnose(ctx context.Context, jobID string, jobModifyIndex uint64) *Diagnostics {
+	logData := log.Data{"job": jobID, "job_modify_index": jobModifyIndex}
+
+	versions, err := d.nomadClient.GetJobVersions(ctx, jobID)
+	if err != nil {
+		log.Warn(ctx, "unable to diagnose failed deployment", log.Data{"job": jobID, "error": err})
+		return nil
+	}
+	var diagnostics *Diagnostics
+	for _, v := range versions.Versions {
+		if v.Version != nil && v.JobModifyIndex != nil && *v.JobModifyIndex == jobModifyIndex {
+			diagnostics = &Diagnostics{Job: jobID, JobVersion: *v.Version}
+			break
+		}
+	}
+	if diagnostics == nil {
+		log.Warn(ctx, "unable to diagnose failed deployment - job version not found", logData)
+		return nil
+	}
+
+	if deployments, err := d.nomadClient.ListDeployments(ctx, jobID); err == nil {
+		for _, deployment := range deployments {
+			if deployment.JobVersion == diagnostics.JobVersion {
+				diagnostics.DeploymentID = deployment.ID
+				diagnostics.StatusDescription = deployment.StatusDescription
+				break
+			}
+		}
+	}
+
+	allocations, err := d.nomadClient.ListAllocations(ctx, jobID)
+	if err != nil {
+		log.Warn(ctx, "unable to list allocations of failed deployment", log.Data{"job": jobID, "error": err})
+	}
+	checks := make(map[string]bool)
+	for _, allocation := range allocations {
+		if len(diagnostics.Allocations) == maxDiagnosedAllocations {
+			break
+		}
+		if allocation.JobVersion != diagnostics.JobVersion || !allocationFailed(&allocation) {
+			continue
+		}
+		diagnostics.Allocations = append(diagnostics.Allocations, d.diagnoseAllocation(ctx, &allocation))
+		failing, err := d.failingChecks(ctx, allocation.ID)
+		if err != nil {
+			log.Warn(ctx, "unable to read checks of failed allocation", log.Data{"allocation": allocation.ID, "error": err})
+		}
+		for _, check := range failing {
+			checks[check] = true
+		}
+	}
+	for check := range checks {
+		diagnostics.FailingChecks = append(diagnostics.FailingChecks, check)
+	}
+	sort.Strings(diagnostics.FailingChecks)
+
+	logData["diagnostics"] = diagnostics
+	log.Warn(ctx, "failed deployment diagnostics", logData)
+	return diagnostics
+}
+
+// diagnoseAllocation summarises the allocation's tasks, fetching the end of
+// stderr for tasks that failed or restarted.
+func (d *Deployment) diagnoseAllocation(ctx context.Context, allocation *api.AllocationListStub) *AllocationDiagnostics {
+	diagnostics := &AllocationDiagnostics{ID: allocation.ID, Node: allocation.NodeName, ClientStatus: allocation.ClientStatus}
+
+	tasks := make([]string, 0, len(allocation.TaskStates))
+	for task := range allocation.TaskStates {
+		tasks = append(tasks, task)
+	}
+	sort.Strings(tasks)
+
+	for _, task := range tasks {
+		state := allocation.TaskStates[task]
+		if state == nil {
+			continue
+		}
+		td := &TaskDiagnostics{Task: task, State: state.State, Failed: state.Failed, Restarts: state.Restarts}
+		events := state.Events
+		if len(events) > maxTaskEvents {
+			events = events[len(events)-maxTaskEvents:]
+		}
+		for _, event := range events {
+			td.Events = append(td.Events, taskEvent(event))
+		}
+		if (state.Failed || state.Restarts > 0) && d.diagnosticLogLines > 0 {
+			td.Stderr = d.stderr(ctx, allocation.ID, task)
+		}
+		diagnostics.Tasks = append(diagnostics.Tasks, td)
+	}
+	return diagnostics
+}
+
+// failingChecks returns the names of the allocation's Nomad service checks that
+// are not passing.
+func (d *Deployment) failingChecks(ctx context.Context, allocationID string) ([]string, error) {
+	checks, err := d.nomadClient.GetAllocationChecks(ctx, allocationID)
+	if err != nil {
+		return nil, err
+	}
+	var failing []string
+	for _, check := range checks {
+		if check.Status != "success" {
+			failing = append(failing, check.Check)
+		}
+	}
+	return failing, nil
+}
+
+// stderr returns the last lines of the task's stderr log.
+func (d *Deployment) stderr(ctx context.Context, allocationID, task string) []string {
+	b, err := d.nomadClient.TaskLogs(ctx, allocationID, task, "stderr", maxLogBytes)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	if len(lines) > d.diagnosticLogLines {
+		lines = lines[len(lines)-d.diagnosticLogLines:]
+	}
+	for i, line := range lines {
+		lines[i] = truncate(line)
+	}
+	return lines
+}
+
+// allocationFailed reports whether the allocation failed, has a failed task or
+// was marked unhealthy by its deployment.
+func allocationFailed(allocation *api.AllocationListStub) bool {
+	switch allocation.ClientStatus {
+	case structs.AllocClientStatusFailed, structs.AllocClientStatusLost:
+		return true
+	}
+	if ds := allocation.DeploymentStatus; ds != nil && ds.Healthy != nil && !*ds.Healthy {
+		return true
+	}
+	for _, state := range allocation.TaskStates {
+		if state != nil && (state.Failed || state.Restarts > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+func taskEvent(event *api.TaskEvent) string {
+	if len(event.DisplayMessage) == 0 {
+		return event.Type
+	}
+	return truncate(event.Type + ": " + event.DisplayMessage)
+}
+
+func truncate(s string) string {
+	if len(s) > maxLineLength {
+		return s[:maxLineLength] + "..."
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	versionsFailed      = `{"Versions": [{"ID": "test", "Version": 2, "JobModifyIndex": 99}, {"ID": "test", "Version": 1, "JobModifyIndex": 50, "Stable": true}]}`
+	deploymentsFailed   = `[{"ID": "d2", "JobVersion": 2, "JobSpecModifyIndex": 99, "Status": "failed", "StatusDescription": "Failed due to unhealthy allocations"}]`
+	allocationsFailed   = `[{"ID": "a1", "JobVersion": 2, "ClientStatus": "running", "DesiredStatus": "run", "TaskStates": {"web": {"State": "running"}}},` + failedAllocation + `, {"ID": "a3", "JobVersion": 1, "ClientStatus": "failed", "DesiredStatus": "stop"}]`
+	failedAllocation    = `{"ID": "a2", "NodeName": "node-1", "JobVersion": 2, "ClientStatus": "failed", "DesiredStatus": "run", "TaskStates": {"web": {"State": "dead", "Failed": true, "Restarts": 2, "Events": [{"Type": "Received"}, {"Type": "Task Setup"}, {"Type": "Started"}, {"Type": "Terminated", "DisplayMessage": "Exit Code: 1"}, {"Type": "Restarting"}, {"Type": "Driver Failure", "DisplayMessage": "failed to pull image"}]}, "sidecar": {"State": "running"}}}`
+	allocationChecks    = `{"c1": {"Check": "healthy", "Status": "failure"}, "c2": {"Check": "alive", "Status": "success"}}`
+	allocationStderr    = "starting\nconnecting to database\npanic: connection refused\n"
+	expectedDiagnostics = &Diagnostics{
+		Job:               "test",
+		JobVersion:        2,
+		DeploymentID:      "d2",
+		StatusDescription: "Failed due to unhealthy allocations",
+		FailingChecks:     []string{"healthy"},
+		Allocations: []*AllocationDiagnostics{{
+			ID:           "a2",
+			Node:         "node-1",
+			ClientStatus: "failed",
+			Tasks: []*TaskDiagnostics{
+				{Task: "sidecar", State: "running"},
+				{
+					Task:     "web",
+					State:    "dead",
+					Failed:   true,
+					Restarts: 2,
+					Events:   []string{"Task Setup", "Started", "Terminated: Exit Code: 1", "Restarting", "Driver Failure: failed to pull image"},
+					Stderr:   []string{"connecting to database", "panic: connection refused"},
+				},
+			},
+		}},
+	}
+)
+
+func TestDiagnose(t *testing.T) {
+	withMocks(func() {
+		Convey("failed deployments are diagnosed as expected", t, func() {
+			ctx := context.Background()
+			httpmock.RegisterResponder("GET", fmt.Sprintf(versionsURL, nomadURL, "test"), httpmock.NewStringResponder(200, versionsFailed))
+			httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, "test"), httpmock.NewStringResponder(200, deploymentsFailed))
+			httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, "test"), httpmock.NewStringResponder(200, allocationsFailed))
+			httpmock.RegisterResponder("GET", fmt.Sprintf(checksURL, nomadURL, "a2"), httpmock.NewStringResponder(200, allocationChecks))
+			httpmock.RegisterResponder("GET", fmt.Sprintf(logsURL, nomadURL, "a2", "web", maxLogBytes), httpmock.NewStringResponder(200, allocationStderr))
+			dep := &Deployment{timeout: shortTimeout, nomadClient: nomadClient, diagnosticLogLines: 2}
+
+			Convey("failed allocations of the job version are summarised", func() {
+				So(dep.diagnose(ctx, "test", 99), ShouldResemble, expectedDiagnostics)
+			})
+
+			Convey("unknown job versions are not diagnosed", func() {
+				So(dep.diagnose(ctx, "test", 100), ShouldBeNil)
+			})
+
+			Convey("only failed or timed out deployments are diagnosed", func() {
+				So(dep.diagnoseFailure(ctx, "test", 99, &PlanError{}), ShouldBeNil)
+				So(dep.diagnoseFailure(ctx, "test", 99, &TimeoutError{Action: "deployment"}), ShouldNotBeNil)
+			})
+
+			Convey("diagnostics are returned in the result of a failed deployment", func() {
+				httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test"), httpmock.NewStringResponder(200, serviceJobInfoSuccess))
+				msg := &engine.Message{ID: "1", Service: "test"}
+				err := dep.run(ctx, msg)
+				So(err, ShouldResemble, &AbortedError{EvaluationID: "12345", CorrelationID: "1"})
+				So(msg.Result, ShouldResemble, expectedDiagnostics)
+			})
+		})
+	})
+}
@@ -0,0 +1,46 @@
+package deployment
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// spool copies an artifact to a temporary file beside the workspace, so it can
+// be verified before anything is extracted. The file is returned rewound along
+// with the artifact's SHA-256 digest, and must be removed by the caller.
+func (w *workspace) spool(r io.Reader) (*os.File, string, error) {
+	f, err := os.CreateTemp(filepath.Dir(w.dir), filepath.Base(w.dir)+".*.bundle")
+	if err != nil {
+		return nil, "", err
+	}
+	h := sha256.New()
+	if w.maxSize > 0 {
+		r = io.LimitReader(r, w.maxSize+1)
+	}
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if err == nil && w.maxSize > 0 && n > w.maxSize {
+		err = &BundleError{Reason: reasonBundleTooLarge}
+	}
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, "", err
+	}
+	return f, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyDigest returns a DigestError if the actual digest does not match the
+// expected digest, which may be prefixed with "sha256:".
+func verifyDigest(artifact, expected, actual string) error {
+	if !strings.EqualFold(strings.TrimPrefix(expected, "sha256:"), actual) {
+		return &DigestError{Actual: "sha256:" + actual, Artifact: artifact, Expected: expected}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package deployment
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/s3"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFetchBundle(t *testing.T) {
+	Convey("bundle digests are verified as expected", t, func() {
+		ctx := context.Background()
+		bundle := tarGz(bundleEntry{name: "test.nomad", body: "job"})
+		sum := sha256.Sum256(bundle)
+		digest := hex.EncodeToString(sum[:])
+
+		root := t.TempDir()
+		s3Client := &s3.ClientMock{GetFunc: func(key string) (io.ReadCloser, *int64, error) {
+			return io.NopCloser(bytes.NewReader(bundle)), nil, nil
+		}}
+		dep := &Deployment{root: root, s3Client: s3Client}
+
+		Convey("matching digests are accepted", func() {
+			msg := &engine.Message{Service: "test", Artifacts: []string{"test.tar.gz"}, Digests: map[string]string{"test.tar.gz": "sha256:" + strings.ToUpper(digest)}}
+			So(dep.fetchBundle(ctx, msg), ShouldBeNil)
+			b, err := os.ReadFile(jobPath(msg))
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "job")
+		})
+
+		Convey("mismatched digests are rejected", func() {
+			msg := &engine.Message{Service: "test", Artifacts: []string{"test.tar.gz"}, Digests: map[string]string{"test.tar.gz": "abc"}}
+			err := dep.fetchBundle(ctx, msg)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "artifact digest verification failed")
+			So(err, ShouldResemble, &DigestError{Actual: "sha256:" + digest, Artifact: "test.tar.gz", Expected: "abc"})
+
+			entries, err := os.ReadDir(msg.Workspace)
+			So(err, ShouldBeNil)
+			So(entries, ShouldBeEmpty)
+			entries, err = os.ReadDir(root)
+			So(err, ShouldBeNil)
+			So(entries, ShouldHaveLength, 1)
+			So(entries[0].Name(), ShouldEqual, filepath.Base(msg.Workspace))
+		})
+
+		Convey("later artifacts with mismatched digests are not extracted", func() {
+			zip := zipped(bundleEntry{name: "other.nomad", body: "job"})
+			s3Client.GetFunc = func(key string) (io.ReadCloser, *int64, error) {
+				if key == "other.zip" {
+					return io.NopCloser(bytes.NewReader(zip)), nil, nil
+				}
+				return io.NopCloser(bytes.NewReader(bundle)), nil, nil
+			}
+			msg := &engine.Message{Service: "test", Artifacts: []string{"test.tar.gz", "other.zip"}, Digests: map[string]string{"test.tar.gz": digest, "other.zip": "abc"}}
+			So(dep.fetchBundle(ctx, msg), ShouldHaveSameTypeAs, &DigestError{})
+
+			entries, err := os.ReadDir(filepath.Join(msg.Workspace, "1"))
+			So(err, ShouldBeNil)
+			So(entries, ShouldBeEmpty)
+		})
+
+		Convey("zip bundles are extracted once verified", func() {
+			zip := zipped(bundleEntry{name: "test.nomad", body: "job"})
+			sum := sha256.Sum256(zip)
+			s3Client.GetFunc = func(key string) (io.ReadCloser, *int64, error) {
+				return io.NopCloser(bytes.NewReader(zip)), nil, nil
+			}
+			msg := &engine.Message{Service: "test", Artifacts: []string{"test.zip"}, Digests: map[string]string{"test.zip": hex.EncodeToString(sum[:])}}
+			So(dep.fetchBundle(ctx, msg), ShouldBeNil)
+			b, err := os.ReadFile(jobPath(msg))
+			So(err, ShouldBeNil)
+			So(string(b), ShouldEqual, "job")
+		})
+
+		Convey("missing digests are allowed unless required", func() {
+			msg := &engine.Message{Service: "test", Artifacts: []string{"test.tar.gz"}}
+			So(dep.fetchBundle(ctx, msg), ShouldBeNil)
+
+			dep.requireDigests = true
+			So(dep.fetchBundle(ctx, msg), ShouldResemble, &DigestError{Artifact: "test.tar.gz"})
+			So(s3Client.GetCalls(), ShouldHaveLength, 1)
+		})
+	})
+}
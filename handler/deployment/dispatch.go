@@ -0,0 +1,101 @@
+package deployment
+
+import (
+	"context"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/message"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/hashicorp/nomad/api"
+)
+
+// DispatchResult represents the result of dispatching a parameterized job, with
+// the exit codes of the child job's tasks. The dispatch meta is not included as
+// it may hold values the caller would not want in responses.
+type DispatchResult struct {
+	Job             string
+	DispatchedJobID string
+	EvaluationID    string
+	Status          string         `json:",omitempty"`
+	ExitCodes       map[string]int `json:",omitempty"`
+}
+
+// DispatchHandler handles dispatch messages that are delegated by the engine.
+// The service's parameterized job is dispatched with the message's meta and
+// payload, and the child job is monitored until it completes.
+// TODO This function will be removed once the new queue has been implemented
+func (d *Deployment) DispatchHandler(ctx context.Context, msg *engine.Message) error {
+	return d.handle(ctx, "DispatchHandler", engineRequest(msg, msg.Service), func(ctx context.Context, d *Deployment) (interface{}, error) {
+		return result(d.dispatch(ctx, msg.ID, msg.Service, msg.Meta, msg.Payload))
+	})
+}
+
+func (d *Deployment) dispatchNew(ctx context.Context, msg *message.MessageSQS) error {
+	return d.handle(ctx, "dispatchNew", sqsRequest(msg, msg.Job), func(ctx context.Context, d *Deployment) (interface{}, error) {
+		return result(d.dispatch(ctx, msg.ID, msg.Job, msg.Meta, msg.Payload))
+	})
+}
+
+// dispatch dispatches the parameterized job and waits for the dispatched child
+// job's allocations to complete. The result has the child job's ID and the exit
+// codes of its tasks, even if it failed.
+func (d *Deployment) dispatch(ctx context.Context, correlationID, jobID string, meta map[string]string, payload string) (*DispatchResult, error) {
+	res, err := d.nomadClient.DispatchJob(ctx, jobID, meta, []byte(payload))
+	if err != nil {
+		return nil, err
+	}
+	result := &DispatchResult{Job: jobID, DispatchedJobID: res.DispatchedJobID, EvaluationID: res.EvalID}
+	logData := log.Data{"job": jobID, "child": res.DispatchedJobID, "evaluation": res.EvalID}
+	log.Info(ctx, "parameterized job dispatched", logData)
+
+	child, err := d.nomadClient.GetJob(ctx, res.DispatchedJobID)
+	if err != nil {
+		return result, err
+	}
+	err = d.successCheckByAllocationsBatch(ctx, correlationID, res.EvalID, res.DispatchedJobID, *child.Version)
+	if ctx.Err() == nil {
+		d.exitStatus(ctx, result)
+	}
+	if err != nil {
+		return result, err
+	}
+	log.Info(ctx, "dispatched job complete", logData)
+	return result, nil
+}
+
+// exitStatus adds the status of the dispatched job's allocation and the exit
+// codes of its tasks to the result.
+func (d *Deployment) exitStatus(ctx context.Context, result *DispatchResult) {
+	allocations, err := d.nomadClient.ListAllocations(ctx, result.DispatchedJobID)
+	if err != nil {
+		log.Error(ctx, "Deployment-exitStatus, d.nomadClient.ListAllocations() error", err, log.Data{"job": result.DispatchedJobID})
+		return
+	}
+	for _, allocation := range allocations {
+		if allocation.EvalID != result.EvaluationID {
+			continue
+		}
+		result.Status = allocation.ClientStatus
+		for task, state := range allocation.TaskStates {
+			if code, ok := exitCode(state); ok {
+				if result.ExitCodes == nil {
+					result.ExitCodes = make(map[string]int)
+				}
+				result.ExitCodes[task] = code
+			}
+		}
+	}
+}
+
+// exitCode returns the exit code of the task's last termination.
+func exitCode(state *api.TaskState) (int, bool) {
+	if state == nil {
+		return 0, false
+	}
+	for i := len(state.Events) - 1; i >= 0; i-- {
+		if state.Events[i].Type == api.TaskTerminated {
+			return state.Events[i].ExitCode, true
+		}
+	}
+	return 0, false
+}
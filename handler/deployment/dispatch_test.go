@@ -0,0 +1,65 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	dispatchedJobID      = "report%2Fdispatch-1"
+	dispatchSuccess      = `{"DispatchedJobID": "report/dispatch-1", "EvalID": "67890"}`
+	dispatchedJobInfo    = `{"ID": "report/dispatch-1", "Name": "report/dispatch-1", "Type": "batch", "Version": 0}`
+	dispatchedCompleted  = `[{"EvalID": "67890", "ID": "54321", "JobVersion": 0, "ClientStatus": "complete", "DesiredStatus": "run", "TaskStates": {"report": {"State": "dead", "Events": [{"Type": "Started"}, {"Type": "Terminated", "ExitCode": 0}]}}}]`
+	dispatchedFailed     = `[{"EvalID": "67890", "ID": "54321", "JobVersion": 0, "ClientStatus": "failed", "DesiredStatus": "run", "TaskStates": {"report": {"State": "dead", "Failed": true, "Events": [{"Type": "Terminated", "ExitCode": 1}, {"Type": "Not Restarting"}]}}}]`
+	dispatchedJobPayload = "id=1"
+)
+
+func TestDispatch(t *testing.T) {
+	withMocks(func() {
+		Convey("parameterized jobs are dispatched", t, func() {
+			ctx := context.Background()
+			var dispatched api.JobDispatchRequest
+			httpmock.RegisterResponder("POST", fmt.Sprintf(dispatchURL, nomadURL, "report"), func(req *http.Request) (*http.Response, error) {
+				b, _ := io.ReadAll(req.Body)
+				json.Unmarshal(b, &dispatched)
+				return httpmock.NewStringResponse(200, dispatchSuccess), nil
+			})
+			httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, dispatchedJobID), httpmock.NewStringResponder(200, dispatchedJobInfo))
+			dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+			meta := map[string]string{"dataset": "cpih"}
+
+			Convey("and monitored until their child completes", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, dispatchedJobID), httpmock.NewStringResponder(200, dispatchedCompleted))
+				res, err := dep.dispatch(ctx, "1", "report", meta, dispatchedJobPayload)
+				So(err, ShouldBeNil)
+				So(dispatched.Meta, ShouldResemble, meta)
+				So(string(dispatched.Payload), ShouldEqual, dispatchedJobPayload)
+				So(res, ShouldResemble, &DispatchResult{
+					Job:             "report",
+					DispatchedJobID: "report/dispatch-1",
+					EvaluationID:    "67890",
+					Status:          "complete",
+					ExitCodes:       map[string]int{"report": 0},
+				})
+				b, _ := json.Marshal(res)
+				So(string(b), ShouldNotContainSubstring, "cpih")
+			})
+
+			Convey("with the exit status of a failed child", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, dispatchedJobID), httpmock.NewStringResponder(200, dispatchedFailed))
+				res, err := dep.dispatch(ctx, "1", "report", meta, "")
+				So(err, ShouldResemble, &AbortedError{EvaluationID: "67890", CorrelationID: "1"})
+				So(res.Status, ShouldEqual, "failed")
+				So(res.ExitCodes, ShouldResemble, map[string]int{"report": 1})
+			})
+		})
+	})
+}
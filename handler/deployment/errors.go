@@ -1,5 +1,7 @@
 package deployment
 
+import "github.com/ONSdigital/dp-deployer/nomadclient"
+
 // AbortedError is an error implementation that includes the ids of the aborted
 // evaluation and message correlation.
 type AbortedError struct {
@@ -11,16 +13,63 @@ func (e *AbortedError) Error() string {
 	return "aborted monitoring deployment"
 }
 
+// BundleError is an error implementation that includes the bundle entry that
+// could not be extracted and why.
+type BundleError struct {
+	Entry  string `json:",omitempty"`
+	Reason string
+}
+
+func (e *BundleError) Error() string {
+	return "invalid deployment bundle"
+}
+
+// CancelError is an error implementation that includes the message ID or
+// service of a cancel message that matched no in-flight deployment.
+type CancelError struct {
+	MessageID string `json:",omitempty"`
+	Service   string `json:",omitempty"`
+}
+
+func (e *CancelError) Error() string {
+	return "no in-flight deployment to cancel"
+}
+
+// CancelledError is an error implementation that includes the ID of the cancel
+// message that cancelled a deployment.
+type CancelledError struct {
+	MessageID string
+}
+
+func (e *CancelledError) Error() string {
+	return "cancelled"
+}
+
 // ClientResponseError is an error implementation that includes the body and status
 // code of the response.
-type ClientResponseError struct {
-	Body       string
-	StatusCode int
-	URL        string
+type ClientResponseError = nomadclient.ResponseError
+
+// ClusterError is an error implementation that includes the name of an unknown
+// Nomad cluster.
+type ClusterError struct {
+	Cluster string
+}
+
+func (e *ClusterError) Error() string {
+	return "unknown nomad cluster"
 }
 
-func (e *ClientResponseError) Error() string {
-	return "unexpected response from client"
+// DigestError is an error implementation that includes the expected and
+// actual digests of an artifact. An empty expected digest means the message did
+// not include one.
+type DigestError struct {
+	Actual   string `json:",omitempty"`
+	Artifact string
+	Expected string
+}
+
+func (e *DigestError) Error() string {
+	return "artifact digest verification failed"
 }
 
 // EvaluationError is an error implementation that includes the evaluation id of the
@@ -43,17 +92,113 @@ func (e *EvaluationAbortedError) Error() string {
 	return "aborted monitoring evaluation"
 }
 
-// PlanError is an error implementation that includes the errors or warnings
+// JobsError is an error implementation that includes the message job that
+// could not be deployed and why.
+type JobsError struct {
+	Job    string
+	Reason string
+}
+
+func (e *JobsError) Error() string {
+	return "invalid message jobs"
+}
+
+// JobspecError is an error implementation that includes the file and location
+// of a jobspec parse error.
+type JobspecError struct {
+	Column  int
+	File    string
+	Line    int
+	Message string
+}
+
+func (e *JobspecError) Error() string {
+	return "failed to parse jobspec"
+}
+
+// PeriodicError is an error implementation that includes the id of a periodic
+// job that could not be forced to run.
+type PeriodicError struct {
+	JobID string
+}
+
+func (e *PeriodicError) Error() string {
+	return "periodic job was not launched"
+}
+
+// PlaceholderError is an error implementation that includes the placeholders
+// left unresolved in a job file.
+type PlaceholderError struct {
+	File         string
+	Placeholders []string
+}
+
+func (e *PlaceholderError) Error() string {
+	return "unresolved placeholders in job file"
+}
+
+// PlanError is an error implementation that includes the errors, warnings or
+// plan policy violations
 type PlanError struct {
-	Errors   string
-	Service  string
-	Warnings string
+	Errors     string
+	Service    string
+	Violations []PlanViolation `json:",omitempty"`
+	Warnings   string
 }
 
 func (e *PlanError) Error() string {
+	if len(e.Violations) > 0 {
+		return "plan violates deployment policy"
+	}
 	return "plan for tasks generated errors or warnings"
 }
 
+// ProtectedJobError is an error implementation that includes the protected job
+// that a message tried to stop.
+type ProtectedJobError struct {
+	Job string
+}
+
+func (e *ProtectedJobError) Error() string {
+	return "job is protected"
+}
+
+// RestartError is an error implementation that includes the ids of the job and
+// the allocation that failed to restart.
+type RestartError struct {
+	AllocationID string `json:",omitempty"`
+	JobID        string
+}
+
+func (e *RestartError) Error() string {
+	return "allocation failed to restart"
+}
+
+// RollbackError is an error implementation that includes the job versions that
+// failed and were restored when a failed deployment was rolled back.
+type RollbackError struct {
+	Cause           string
+	FailedVersion   uint64
+	JobID           string
+	RestoredVersion uint64
+}
+
+func (e *RollbackError) Error() string {
+	return "deployment failed and was rolled back"
+}
+
+// ScaleError is an error implementation that includes the task group and count
+// of an invalid scale message and why it is invalid.
+type ScaleError struct {
+	Count  *int `json:",omitempty"`
+	Group  string
+	Reason string
+}
+
+func (e *ScaleError) Error() string {
+	return "invalid scale request"
+}
+
 // TimeoutError is an error implementation that includes the action that timed out.
 type TimeoutError struct {
 	Action string
@@ -0,0 +1,90 @@
+package deployment
+
+import (
+	"context"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/message"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// request is what a handler needs of a message, whichever queue it came from:
+// where it is handled, the services it is in flight for and where its result
+// is recorded.
+type request struct {
+	id        string
+	cluster   string
+	namespace string
+	region    string
+	signer    string
+	services  []string
+	result    *interface{}
+}
+
+// engineRequest returns the request of a message delegated by the engine.
+// TODO This function will be removed once the new queue has been implemented
+func engineRequest(msg *engine.Message, services ...string) request {
+	return request{
+		id:        msg.ID,
+		cluster:   msg.Cluster,
+		namespace: msg.Namespace,
+		region:    msg.Region,
+		signer:    msg.Signer,
+		services:  services,
+		result:    &msg.Result,
+	}
+}
+
+// sqsRequest returns the request of a message received from the new queue.
+func sqsRequest(msg *message.MessageSQS, services ...string) request {
+	return request{
+		id:        msg.ID,
+		cluster:   msg.Cluster,
+		namespace: msg.Namespace,
+		region:    msg.Region,
+		signer:    msg.Signer,
+		services:  services,
+		result:    &msg.Result,
+	}
+}
+
+// handle calls f with a copy of the deployment that targets the request's
+// cluster, namespace and region, authorised for its signer. The request is
+// tracked while f runs so it can be cancelled, unless it has no services. Any
+// result f returns is recorded, even if it failed.
+func (d *Deployment) handle(ctx context.Context, name string, r request, f func(context.Context, *Deployment) (interface{}, error)) (err error) {
+	c, err := d.cluster(r.cluster)
+	if err != nil {
+		log.Error(ctx, "Deployment-handle, d.cluster() error", err, log.Data{"handler": name, "cluster": r.cluster})
+		return err
+	}
+	d = c.target(r.namespace, r.region).authorise(r.signer)
+
+	if len(r.services) > 0 {
+		var done func()
+		ctx, done = d.track(ctx, r.id, r.services...)
+		defer func() {
+			err = cancelled(ctx, err)
+			done()
+		}()
+	}
+
+	res, err := f(ctx, d)
+	if res != nil {
+		*r.result = res
+	}
+	if err != nil {
+		log.Error(ctx, "Deployment-handle, f() error", err, log.Data{"handler": name, "services": r.services})
+		return err
+	}
+	return nil
+}
+
+// result returns a handler's result, which is nil rather than a typed nil when
+// there is none.
+func result[T any](res *T, err error) (interface{}, error) {
+	if res == nil {
+		return nil, err
+	}
+	return res, err
+}
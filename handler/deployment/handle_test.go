@@ -0,0 +1,50 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/message"
+	"github.com/ONSdigital/dp-deployer/nomadclient"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHandle(t *testing.T) {
+	Convey("requests are handled against their target", t, func() {
+		ctx := context.Background()
+		drClient := nomadclient.New("https://nomad.dr:4646", "dr", nil, 0, 0)
+		dep := &Deployment{nomadClient: nomadclient.New(nomadURL, "default", nil, 0, 0), inflight: newInflight(), clusters: map[string]*Cluster{"dr": {API: drClient}}}
+
+		Convey("on their cluster, namespace and region", func() {
+			msg := &engine.Message{ID: "1", Cluster: "dr", Namespace: "dp", Region: "eu", Service: "test"}
+			err := dep.handle(ctx, "test", engineRequest(msg, msg.Service), func(ctx context.Context, d *Deployment) (interface{}, error) {
+				So(d.nomadClient, ShouldResemble, drClient.Target("dp", "eu"))
+				So(dep.inflight.messages, ShouldContainKey, "1")
+				return &StopResult{Job: "test"}, nil
+			})
+			So(err, ShouldBeNil)
+			So(msg.Result, ShouldResemble, &StopResult{Job: "test"})
+			So(dep.inflight.messages, ShouldBeEmpty)
+		})
+
+		Convey("untracked without services", func() {
+			msg := &message.MessageSQS{ID: "1", Job: "test"}
+			err := dep.handle(ctx, "test", sqsRequest(msg), func(context.Context, *Deployment) (interface{}, error) {
+				So(dep.inflight.messages, ShouldBeEmpty)
+				return result((*StopResult)(nil), errors.New("failed"))
+			})
+			So(err, ShouldResemble, errors.New("failed"))
+			So(msg.Result, ShouldBeNil)
+		})
+
+		Convey("unless their cluster is unknown", func() {
+			msg := &engine.Message{ID: "1", Cluster: "unknown"}
+			err := dep.handle(ctx, "test", engineRequest(msg), func(context.Context, *Deployment) (interface{}, error) {
+				panic("handled")
+			})
+			So(err, ShouldResemble, &ClusterError{Cluster: "unknown"})
+		})
+	})
+}
@@ -0,0 +1,234 @@
+package deployment
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/nomadclient"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// Job failure policies.
+const (
+	// FailurePolicyRollback reverts the jobs a message has already deployed to
+	// their previous version when a later job fails. Jobs that did not exist
+	// before the message are stopped.
+	FailurePolicyRollback = "rollback"
+	// FailurePolicyStop stops the jobs a message has already deployed when a
+	// later job fails.
+	FailurePolicyStop = "stop"
+)
+
+// Job deployment statuses.
+const (
+	JobDeployed   = "deployed"
+	JobFailed     = "failed"
+	JobRolledBack = "rolled-back"
+	JobSkipped    = "skipped"
+	JobStopped    = "stopped"
+)
+
+// JobResult represents the outcome of deploying one of a message's jobs.
+type JobResult struct {
+	Job         string
+	Status      string
+	Error       string       `json:",omitempty"`
+	Diagnostics *Diagnostics `json:",omitempty"`
+}
+
+// deployedJob represents a job deployed by a message and the version it
+// replaced, which is nil for new jobs.
+type deployedJob struct {
+	result   *JobResult
+	previous *uint64
+}
+
+// deployJobs plans every job in the message, then runs them in dependency order,
+// waiting for each one to be healthy. When a job fails the jobs already deployed
+// are rolled back or stopped, depending on the job failure policy, and the
+// remaining jobs are skipped.
+func (d *Deployment) deployJobs(ctx context.Context, msg *engine.Message) error {
+	jobs, err := orderJobs(msg.Jobs)
+	if err != nil {
+		log.Error(ctx, "Deployment-deployJobs, orderJobs() error", err)
+		return err
+	}
+
+	results := make([]*JobResult, len(jobs))
+	for i, job := range jobs {
+		results[i] = &JobResult{Job: job.Name, Status: JobSkipped}
+	}
+	msg.Result = results
+
+	msgs := make([]*engine.Message, len(jobs))
+	for i, job := range jobs {
+		jobMsg, err := jobMessage(msg, job)
+		if err == nil {
+			err = d.substitute(ctx, jobMsg)
+		}
+		if err == nil {
+			err = d.plan(ctx, jobMsg)
+		}
+		if err != nil {
+			log.Error(ctx, "Deployment-deployJobs, plan error", err, log.Data{"job": job.Name})
+			results[i].Status, results[i].Error = JobFailed, err.Error()
+			return err
+		}
+		msgs[i] = jobMsg
+	}
+
+	var deployed []deployedJob
+	for i, jobMsg := range msgs {
+		previous, err := d.jobVersion(ctx, jobMsg.Service)
+		if err == nil {
+			err = d.run(ctx, jobMsg)
+		}
+		if err != nil {
+			log.Error(ctx, "Deployment-deployJobs, run error", err, log.Data{"job": jobMsg.Service})
+			results[i].Status, results[i].Error = JobFailed, err.Error()
+			if diagnostics, ok := jobMsg.Result.(*Diagnostics); ok {
+				results[i].Diagnostics = diagnostics
+			}
+			d.undeployJobs(ctx, msg.ID, deployed)
+			return err
+		}
+		results[i].Status = JobDeployed
+		deployed = append(deployed, deployedJob{result: results[i], previous: previous})
+	}
+	return nil
+}
+
+// undeployJobs rolls back or stops the deployed jobs in reverse order. The jobs
+// are still undeployed when the message was cancelled, but not on shutdown.
+func (d *Deployment) undeployJobs(ctx context.Context, correlationID string, deployed []deployedJob) {
+	ctx, stop := rollbackContext(ctx)
+	defer stop()
+
+	for i := len(deployed) - 1; i >= 0; i-- {
+		job := deployed[i]
+		jobID := job.result.Job
+
+		if job.previous != nil && d.jobFailurePolicy != FailurePolicyStop {
+			jobInfo, err := d.nomadClient.GetJob(ctx, jobID)
+			if err == nil {
+				err = d.revertTo(ctx, correlationID, jobID, jobInfo, *job.previous)
+			}
+			if err != nil {
+				log.Error(ctx, "Deployment-undeployJobs, d.revertTo() error", err, log.Data{"job": jobID})
+				job.result.Error = err.Error()
+				continue
+			}
+			job.result.Status = JobRolledBack
+			continue
+		}
+
+		if _, err := d.nomadClient.DeregisterJob(ctx, jobID, false); err != nil {
+			log.Error(ctx, "Deployment-undeployJobs, d.nomadClient.DeregisterJob() error", err, log.Data{"job": jobID})
+			job.result.Error = err.Error()
+			continue
+		}
+		job.result.Status = JobStopped
+	}
+}
+
+// jobVersion returns the current version of the job, or nil if it does not
+// exist.
+func (d *Deployment) jobVersion(ctx context.Context, jobID string) (*uint64, error) {
+	jobInfo, err := d.nomadClient.GetJob(ctx, jobID)
+	if err != nil {
+		if nomadclient.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return jobInfo.Version, nil
+}
+
+// jobMessage returns a message for deploying one of the message's jobs from the
+// workspace directory of its artifact.
+func jobMessage(msg *engine.Message, job engine.Job) (*engine.Message, error) {
+	artifact := job.Artifact
+	dir := msg.Workspace
+	switch {
+	case len(msg.Artifacts) == 1 && (len(artifact) == 0 || artifact == msg.Artifacts[0]):
+		artifact = msg.Artifacts[0]
+	case len(msg.Artifacts) > 1:
+		i := indexOf(msg.Artifacts, artifact)
+		if i < 0 {
+			return nil, &JobsError{Job: job.Name, Reason: "unknown artifact " + artifact}
+		}
+		dir = filepath.Join(msg.Workspace, strconv.Itoa(i))
+	default:
+		return nil, &JobsError{Job: job.Name, Reason: "unknown artifact " + artifact}
+	}
+
+	jobMsg := *msg
+	jobMsg.Artifacts = []string{artifact}
+	jobMsg.Jobs = nil
+	jobMsg.JobSpec = nil
+	jobMsg.Result = nil
+	jobMsg.Service = job.Name
+	jobMsg.Workspace = dir
+	return &jobMsg, nil
+}
+
+// orderJobs returns the jobs in dependency order. Jobs are otherwise kept in
+// their declared order.
+func orderJobs(jobs []engine.Job) ([]engine.Job, error) {
+	names := make(map[string]bool)
+	for _, job := range jobs {
+		if len(job.Name) == 0 || names[job.Name] {
+			return nil, &JobsError{Job: job.Name, Reason: "missing or duplicate job name"}
+		}
+		names[job.Name] = true
+	}
+	for _, job := range jobs {
+		for _, dep := range job.DependsOn {
+			if !names[dep] {
+				return nil, &JobsError{Job: job.Name, Reason: "unknown dependency " + dep}
+			}
+		}
+	}
+
+	ordered := make([]engine.Job, 0, len(jobs))
+	done := make(map[string]bool)
+	for len(ordered) < len(jobs) {
+		next := -1
+		for i, job := range jobs {
+			if !done[job.Name] && dependenciesDone(job, done) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			for _, job := range jobs {
+				if !done[job.Name] {
+					return nil, &JobsError{Job: job.Name, Reason: "dependency cycle"}
+				}
+			}
+		}
+		ordered = append(ordered, jobs[next])
+		done[jobs[next].Name] = true
+	}
+	return ordered, nil
+}
+
+func dependenciesDone(job engine.Job, done map[string]bool) bool {
+	for _, dep := range job.DependsOn {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,231 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/nomadclient"
+	"github.com/hashicorp/nomad/api"
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOrderJobs(t *testing.T) {
+	Convey("jobs are ordered as expected", t, func() {
+		names := func(jobs []engine.Job) []string {
+			var n []string
+			for _, job := range jobs {
+				n = append(n, job.Name)
+			}
+			return n
+		}
+
+		Convey("jobs without dependencies keep their declared order", func() {
+			jobs, err := orderJobs([]engine.Job{{Name: "b"}, {Name: "a"}})
+			So(err, ShouldBeNil)
+			So(names(jobs), ShouldResemble, []string{"b", "a"})
+		})
+
+		Convey("jobs are deployed after their dependencies", func() {
+			jobs, err := orderJobs([]engine.Job{{Name: "web", DependsOn: []string{"api"}}, {Name: "api", DependsOn: []string{"db"}}, {Name: "db"}, {Name: "other"}})
+			So(err, ShouldBeNil)
+			So(names(jobs), ShouldResemble, []string{"db", "api", "web", "other"})
+		})
+
+		Convey("invalid dependencies are rejected", func() {
+			_, err := orderJobs([]engine.Job{{Name: "a"}, {Name: "a"}})
+			So(err, ShouldResemble, &JobsError{Job: "a", Reason: "missing or duplicate job name"})
+
+			_, err = orderJobs([]engine.Job{{Name: "a", DependsOn: []string{"b"}}})
+			So(err, ShouldResemble, &JobsError{Job: "a", Reason: "unknown dependency b"})
+
+			_, err = orderJobs([]engine.Job{{Name: "c"}, {Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}})
+			So(err, ShouldResemble, &JobsError{Job: "a", Reason: "dependency cycle"})
+		})
+	})
+}
+
+func TestJobVersion(t *testing.T) {
+	Convey("the current version of a job is returned", t, func() {
+		version := uint64(3)
+		client := &nomadclient.ClientMock{
+			GetJobFunc: func(ctx context.Context, jobID string) (*api.Job, error) {
+				if jobID == "missing" {
+					return nil, &nomadclient.ResponseError{StatusCode: http.StatusNotFound}
+				}
+				if jobID == "broken" {
+					return nil, &nomadclient.ResponseError{StatusCode: http.StatusBadGateway}
+				}
+				return &api.Job{ID: &jobID, Version: &version}, nil
+			},
+		}
+		dep := &Deployment{nomadClient: client}
+
+		v, err := dep.jobVersion(context.Background(), "test")
+		So(err, ShouldBeNil)
+		So(*v, ShouldEqual, 3)
+
+		Convey("or nil if the job does not exist", func() {
+			v, err := dep.jobVersion(context.Background(), "missing")
+			So(err, ShouldBeNil)
+			So(v, ShouldBeNil)
+		})
+
+		Convey("and other errors are returned", func() {
+			_, err := dep.jobVersion(context.Background(), "broken")
+			So(err, ShouldResemble, &nomadclient.ResponseError{StatusCode: http.StatusBadGateway})
+		})
+	})
+}
+
+func TestDeployJobs(t *testing.T) {
+	withMocks(func() {
+		Convey("multi-job messages are deployed as expected", t, func() {
+			ctx := context.Background()
+			dir := t.TempDir()
+			for _, name := range []string{"a", "b", "c"} {
+				So(os.WriteFile(filepath.Join(dir, name+".nomad"), []byte(`job "`+name+`" {}`), 0600), ShouldBeNil)
+			}
+			msg := &engine.Message{
+				ID:        "1",
+				Artifacts: []string{"bundle.tar.gz"},
+				Jobs:      []engine.Job{{Name: "c", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}, {Name: "a"}},
+				Workspace: dir,
+			}
+
+			runs := 0
+			httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), func(req *http.Request) (*http.Response, error) {
+				runs++
+				return httpmock.NewStringResponse(200, jobSuccess), nil
+			})
+			for _, name := range []string{"a", "b", "c"} {
+				httpmock.RegisterResponder("POST", fmt.Sprintf(planURL, nomadURL, name), httpmock.NewStringResponder(200, planSuccess))
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, name), httpmock.NewStringResponder(200, allocationsSuccess))
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, name), httpmock.NewStringResponder(200, systemJobInfoSuccess))
+			}
+			dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+
+			Convey("jobs are run in dependency order", func() {
+				err := dep.deployJobs(ctx, msg)
+				So(err, ShouldBeNil)
+				So(runs, ShouldEqual, 3)
+				So(msg.Result, ShouldResemble, []*JobResult{{Job: "a", Status: JobDeployed}, {Job: "b", Status: JobDeployed}, {Job: "c", Status: JobDeployed}})
+			})
+
+			Convey("no jobs are run when one fails to plan", func() {
+				httpmock.RegisterResponder("POST", fmt.Sprintf(planURL, nomadURL, "c"), httpmock.NewStringResponder(200, planErrors))
+				err := dep.deployJobs(ctx, msg)
+				So(err, ShouldNotBeNil)
+				So(runs, ShouldEqual, 0)
+				So(msg.Result.([]*JobResult)[2], ShouldResemble, &JobResult{Job: "c", Status: JobFailed, Error: "plan for tasks generated errors or warnings"})
+			})
+
+			Convey("deployed jobs are stopped when a later job fails", func() {
+				stopped := false
+				httpmock.RegisterResponder("DELETE", fmt.Sprintf(infoURL, nomadURL, "a"), func(req *http.Request) (*http.Response, error) {
+					stopped = true
+					return httpmock.NewStringResponse(200, `{"EvalID": "23456"}`), nil
+				})
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "a"), func(req *http.Request) (*http.Response, error) {
+					if runs == 0 {
+						return httpmock.NewStringResponder(404, "job not found")(req)
+					}
+					return httpmock.NewStringResponse(200, systemJobInfoSuccess), nil
+				})
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, "b"), httpmock.NewStringResponder(200, allocationsError))
+
+				err := dep.deployJobs(ctx, msg)
+				So(err, ShouldNotBeNil)
+				So(stopped, ShouldBeTrue)
+				So(runs, ShouldEqual, 2)
+				results := msg.Result.([]*JobResult)
+				So(results[0], ShouldResemble, &JobResult{Job: "a", Status: JobStopped})
+				So(results[1].Status, ShouldEqual, JobFailed)
+				So(results[2], ShouldResemble, &JobResult{Job: "c", Status: JobSkipped})
+			})
+
+			Convey("deployed jobs are rolled back to their previous version when a later job fails", func() {
+				var revertRequest api.JobRevertRequest
+				httpmock.RegisterResponder("POST", fmt.Sprintf(revertURL, nomadURL, "a"), func(req *http.Request) (*http.Response, error) {
+					b, _ := io.ReadAll(req.Body)
+					json.Unmarshal(b, &revertRequest)
+					return httpmock.NewStringResponse(200, revertSuccess), nil
+				})
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "a"), func(req *http.Request) (*http.Response, error) {
+					switch {
+					case runs == 0:
+						return httpmock.NewStringResponse(200, `{"ID": "a", "Type": "system", "Version": 1}`), nil
+					case revertRequest.JobID != "":
+						return httpmock.NewStringResponse(200, systemJobInfoReverted), nil
+					}
+					return httpmock.NewStringResponse(200, systemJobInfoSuccess), nil
+				})
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, "a"), func(req *http.Request) (*http.Response, error) {
+					if revertRequest.JobID != "" {
+						return httpmock.NewStringResponse(200, allocationsRevertedVersion), nil
+					}
+					return httpmock.NewStringResponse(200, allocationsSuccess), nil
+				})
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, "b"), httpmock.NewStringResponder(200, allocationsError))
+
+				err := dep.deployJobs(ctx, msg)
+				So(err, ShouldNotBeNil)
+				So(revertRequest.JobVersion, ShouldEqual, 1)
+				So(*revertRequest.EnforcePriorVersion, ShouldEqual, 2)
+				So(msg.Result.([]*JobResult)[0], ShouldResemble, &JobResult{Job: "a", Status: JobRolledBack})
+			})
+
+			Convey("deployed jobs are rolled back when the message is cancelled mid-deploy", func() {
+				ctx, cancel := context.WithCancelCause(ctx)
+				defer cancel(nil)
+				var revertRequest api.JobRevertRequest
+				httpmock.RegisterResponder("POST", fmt.Sprintf(revertURL, nomadURL, "a"), func(req *http.Request) (*http.Response, error) {
+					if err := req.Context().Err(); err != nil {
+						return nil, err
+					}
+					b, _ := io.ReadAll(req.Body)
+					json.Unmarshal(b, &revertRequest)
+					return httpmock.NewStringResponse(200, revertSuccess), nil
+				})
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "a"), func(req *http.Request) (*http.Response, error) {
+					switch {
+					case runs == 0:
+						return httpmock.NewStringResponse(200, `{"ID": "a", "Type": "system", "Version": 1}`), nil
+					case revertRequest.JobID != "":
+						return httpmock.NewStringResponse(200, systemJobInfoReverted), nil
+					}
+					return httpmock.NewStringResponse(200, systemJobInfoSuccess), nil
+				})
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, "a"), func(req *http.Request) (*http.Response, error) {
+					if revertRequest.JobID != "" {
+						return httpmock.NewStringResponse(200, allocationsRevertedVersion), nil
+					}
+					return httpmock.NewStringResponse(200, allocationsSuccess), nil
+				})
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, "b"), func(req *http.Request) (*http.Response, error) {
+					cancel(&CancelledError{MessageID: "1"})
+					return httpmock.NewStringResponse(200, allocationsPending), nil
+				})
+
+				err := dep.deployJobs(ctx, msg)
+				So(err, ShouldNotBeNil)
+				So(runs, ShouldEqual, 2)
+				So(revertRequest.JobVersion, ShouldEqual, 1)
+				So(msg.Result.([]*JobResult)[0], ShouldResemble, &JobResult{Job: "a", Status: JobRolledBack})
+			})
+
+			Convey("jobs must be from one of the message's artifacts", func() {
+				msg.Jobs = []engine.Job{{Name: "a", Artifact: "other.tar.gz"}}
+				err := dep.deployJobs(ctx, msg)
+				So(err, ShouldResemble, &JobsError{Job: "a", Reason: "unknown artifact other.tar.gz"})
+			})
+		})
+	})
+}
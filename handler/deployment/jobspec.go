@@ -0,0 +1,146 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ONSdigital/dp-deployer/nomadclient"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/jobspec"
+)
+
+// hcl2ErrorPattern matches the location at the start of an HCL2 diagnostic,
+// e.g. "input.hcl:3,5-10: Unsupported argument; ...".
+var hcl2ErrorPattern = regexp.MustCompile(`[^:\s]+:(\d+),(\d+)(?:-\d+)?: (.*)`)
+
+// jsonFromFile parses the jobspec as HCL2 with its variables, falling back to
+// HCL1 for jobspecs that Nomad rejects as not valid HCL2. Variables come from the deployer
+// config, then the bundle's environment vars file, then the message, with later
+// sources taking precedence.
+func (d *Deployment) jsonFromFile(ctx context.Context, jobPath string, vars map[string]string) ([]byte, error) {
+	b, err := os.ReadFile(jobPath)
+	if err != nil {
+		return nil, err
+	}
+
+	variables, err := d.variables(filepath.Dir(jobPath), vars)
+	if err != nil {
+		return nil, err
+	}
+
+	job, hcl2Err := d.nomadClient.ParseJob(ctx, &api.JobsParseRequest{JobHCL: string(b), Variables: variables})
+	if hcl2Err == nil {
+		return json.Marshal(payload{job})
+	}
+	var cre *nomadclient.ResponseError
+	if !errors.As(hcl2Err, &cre) || cre.StatusCode != http.StatusBadRequest {
+		return nil, hcl2Err
+	}
+
+	p, err := jobspec.Parse(strings.NewReader(string(b)))
+	if err != nil {
+		// HCL1 is only a fallback so the HCL2 error is reported
+		return nil, jobspecError(jobPath, hcl2Err)
+	}
+	log.Warn(ctx, "jobspec parsed as deprecated HCL1", log.Data{"file": jobPath})
+	return json.Marshal(payload{p})
+}
+
+// variables returns the job variables as the content of an HCL2 variables file.
+func (d *Deployment) variables(dir string, vars map[string]string) (string, error) {
+	values := make(map[string]string)
+	for k, v := range d.variablesConfig {
+		values[k] = quoteVariable(v)
+	}
+
+	if len(d.environment) > 0 {
+		path := filepath.Join(dir, d.environment+".vars")
+		b, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		if err == nil {
+			file, diags := hclsyntax.ParseConfig(b, path, hcl.InitialPos)
+			if diags.HasErrors() {
+				return "", diagnosticsError(diags)
+			}
+			attrs, diags := file.Body.JustAttributes()
+			if diags.HasErrors() {
+				return "", diagnosticsError(diags)
+			}
+			for name, attr := range attrs {
+				values[name] = string(attr.Expr.Range().SliceBytes(b))
+			}
+		}
+	}
+
+	for k, v := range vars {
+		values[k] = quoteVariable(v)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var content strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&content, "%s = %s\n", name, values[name])
+	}
+	return content.String(), nil
+}
+
+// quoteVariable returns the value as an HCL2 string literal without template
+// interpolation.
+func quoteVariable(v string) string {
+	q := strconv.Quote(v)
+	q = strings.ReplaceAll(q, "${", "$${")
+	return strings.ReplaceAll(q, "%{", "%%{")
+}
+
+// jobspecError returns a JobspecError naming the file and the location of the
+// first HCL2 error reported by Nomad.
+func jobspecError(jobPath string, err error) error {
+	var cre *nomadclient.ResponseError
+	if !errors.As(err, &cre) {
+		return err
+	}
+	e := &JobspecError{File: jobPath, Message: strings.TrimSpace(cre.Body)}
+	if m := hcl2ErrorPattern.FindStringSubmatch(e.Message); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+		e.Column, _ = strconv.Atoi(m[2])
+		e.Message = m[3]
+	}
+	return e
+}
+
+// diagnosticsError returns a JobspecError for the first error diagnostic.
+func diagnosticsError(diags hcl.Diagnostics) error {
+	for _, diag := range diags {
+		if diag.Severity != hcl.DiagError {
+			continue
+		}
+		e := &JobspecError{Message: diag.Summary}
+		if len(diag.Detail) > 0 {
+			e.Message += "; " + diag.Detail
+		}
+		if diag.Subject != nil {
+			e.File, e.Line, e.Column = diag.Subject.Filename, diag.Subject.Start.Line, diag.Subject.Start.Column
+		}
+		return e
+	}
+	return diags
+}
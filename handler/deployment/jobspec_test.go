@@ -0,0 +1,151 @@
+package deployment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/nomadclient"
+	"github.com/ONSdigital/dp-deployer/s3"
+	"github.com/hashicorp/nomad/api"
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var hcl1Job = `job "test" {
+  datacenters = ["eu-west-1"]
+  group "web" {
+    task "test-web" {
+      driver = "docker"
+    }
+  }
+}
+`
+
+func TestJobspec(t *testing.T) {
+	withMocks(func() {
+		Convey("jobspecs are parsed as expected", t, func() {
+			dir := t.TempDir()
+			jobPath := filepath.Join(dir, "test.nomad")
+			So(os.WriteFile(jobPath, []byte(hcl1Job), 0600), ShouldBeNil)
+
+			var parseRequest api.JobsParseRequest
+			httpmock.RegisterResponder("POST", fmt.Sprintf(parseURL, nomadURL), func(req *http.Request) (*http.Response, error) {
+				b, _ := io.ReadAll(req.Body)
+				json.Unmarshal(b, &parseRequest)
+				return httpmock.NewStringResponse(200, `{"ID": "test", "Name": "test"}`), nil
+			})
+
+			dep := &Deployment{nomadClient: nomadClient, environment: "staging"}
+
+			Convey("hcl2 jobspecs are parsed by nomad", func() {
+				j, err := dep.jsonFromFile(context.Background(), jobPath, nil)
+				So(err, ShouldBeNil)
+				So(string(j), ShouldContainSubstring, `"ID":"test"`)
+				So(parseRequest.JobHCL, ShouldEqual, hcl1Job)
+				So(parseRequest.Variables, ShouldBeEmpty)
+			})
+
+			Convey("variables are merged from config, the vars file and the message", func() {
+				vars := "image = \"test:1\"\ncount = 2\nregion = \"eu\"\n"
+				So(os.WriteFile(filepath.Join(dir, "staging.vars"), []byte(vars), 0600), ShouldBeNil)
+				dep.variablesConfig = map[string]string{"region": "uk", "datacenter": "eu-west-1"}
+
+				_, err := dep.jsonFromFile(context.Background(), jobPath, map[string]string{"image": "test:${2}"})
+				So(err, ShouldBeNil)
+				So(parseRequest.Variables, ShouldEqual, "count = 2\ndatacenter = \"eu-west-1\"\nimage = \"test:$${2}\"\nregion = \"eu\"\n")
+			})
+
+			Convey("invalid vars files are reported with their location", func() {
+				So(os.WriteFile(filepath.Join(dir, "staging.vars"), []byte("image = \n"), 0600), ShouldBeNil)
+
+				_, err := dep.jsonFromFile(context.Background(), jobPath, nil)
+				So(err, ShouldNotBeNil)
+				So(err.(*JobspecError).File, ShouldEqual, filepath.Join(dir, "staging.vars"))
+				So(err.(*JobspecError).Line, ShouldEqual, 1)
+			})
+
+			Convey("hcl1 jobspecs are parsed when nomad rejects them", func() {
+				httpmock.RegisterResponder("POST", fmt.Sprintf(parseURL, nomadURL), httpmock.NewStringResponder(400, "input.hcl:1,1-4: Unsupported block type"))
+
+				j, err := dep.jsonFromFile(context.Background(), jobPath, nil)
+				So(err, ShouldBeNil)
+				var p payload
+				So(json.Unmarshal(j, &p), ShouldBeNil)
+				So(*p.Job.ID, ShouldEqual, "test")
+				So(p.Job.Datacenters, ShouldResemble, []string{"eu-west-1"})
+			})
+
+			Convey("other nomad errors are returned without falling back to hcl1", func() {
+				httpmock.RegisterResponder("POST", fmt.Sprintf(parseURL, nomadURL), httpmock.NewStringResponder(403, "Permission denied"))
+
+				_, err := dep.jsonFromFile(context.Background(), jobPath, nil)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unexpected response from client")
+				So(err.(*nomadclient.ResponseError).StatusCode, ShouldEqual, 403)
+			})
+
+			Convey("hcl2 errors name the file and location", func() {
+				So(os.WriteFile(jobPath, []byte("job \"test\" {\n  group \"web\" {\n    nope = 1\n"), 0600), ShouldBeNil)
+				httpmock.RegisterResponder("POST", fmt.Sprintf(parseURL, nomadURL), httpmock.NewStringResponder(400, "input.hcl:3,5-9: Unsupported argument; An argument named \"nope\" is not expected here.\n"))
+
+				_, err := dep.jsonFromFile(context.Background(), jobPath, nil)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "failed to parse jobspec")
+				So(err, ShouldResemble, &JobspecError{
+					Column:  5,
+					File:    jobPath,
+					Line:    3,
+					Message: "Unsupported argument; An argument named \"nope\" is not expected here.",
+				})
+			})
+		})
+	})
+}
+
+func TestJobspecParsedOnce(t *testing.T) {
+	Convey("a message's jobspec is only parsed once", t, func() {
+		defer func(f func(*Deployment, context.Context, string, map[string]string) ([]byte, error)) { jsonFrom = f }(jsonFrom)
+		parsed := 0
+		jsonFrom = func(*Deployment, context.Context, string, map[string]string) ([]byte, error) {
+			parsed++
+			return []byte(`{"Job": {"ID": "test"}}`), nil
+		}
+
+		dep := &Deployment{}
+		msg := &engine.Message{ID: "1", Service: "test"}
+		for i := 0; i < 2; i++ {
+			j, err := dep.jsonFormat(context.Background(), msg)
+			So(err, ShouldBeNil)
+			So(string(j), ShouldEqual, `{"Job": {"ID": "test"}}`)
+		}
+		So(parsed, ShouldEqual, 1)
+	})
+}
+
+func TestJobspecHandler(t *testing.T) {
+	withMocks(func() {
+		Convey("invalid jobspecs are reported by the handler", t, func() {
+			jsonFrom = (*Deployment).jsonFromFile
+			bundle := tarGz(bundleEntry{name: "test.nomad", body: "job \"test\" {\n  group \"web\" {\n    nope = 1\n"})
+			s3Client := &s3.ClientMock{GetFunc: func(string) (io.ReadCloser, *int64, error) {
+				return io.NopCloser(bytes.NewReader(bundle)), nil, nil
+			}}
+			httpmock.RegisterResponder("POST", fmt.Sprintf(parseURL, nomadURL), httpmock.NewStringResponder(400, "input.hcl:3,5-9: Unsupported argument; An argument named \"nope\" is not expected here.\n"))
+
+			dep := &Deployment{root: t.TempDir(), s3Client: s3Client, nomadClient: nomadClient}
+			err := dep.Handler(context.Background(), &engine.Message{ID: "1", Service: "test", Artifacts: []string{"test.tar.gz"}})
+			So(err, ShouldHaveSameTypeAs, &JobspecError{})
+			So(err.(*JobspecError).Line, ShouldEqual, 3)
+			So(httpmock.GetCallCountInfo()["POST "+nomadURL+"/v1/job/test/plan"], ShouldEqual, 0)
+			So(httpmock.GetCallCountInfo()["POST "+fmt.Sprintf(runURL, nomadURL)], ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,31 @@
+package deployment
+
+import (
+	"context"
+
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// forceRun launches a child job of the periodic job now and waits for its
+// allocations to complete, so that a broken periodic job fails its deployment
+// instead of its next scheduled run.
+func (d *Deployment) forceRun(ctx context.Context, correlationID, jobID string) error {
+	evaluationID, err := d.nomadClient.ForcePeriodic(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if len(evaluationID) == 0 {
+		return &PeriodicError{JobID: jobID}
+	}
+
+	evaluation, err := d.nomadClient.GetEvaluation(ctx, evaluationID)
+	if err != nil {
+		return err
+	}
+	child, err := d.nomadClient.GetJob(ctx, evaluation.JobID)
+	if err != nil {
+		return err
+	}
+	log.Info(ctx, "periodic job forced to run", log.Data{"job": jobID, "child": evaluation.JobID, "evaluation": evaluationID})
+	return d.successCheckByAllocationsBatch(ctx, correlationID, evaluationID, evaluation.JobID, *child.Version)
+}
@@ -0,0 +1,53 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	childJobID          = "test%2Fperiodic-1"
+	forceSuccess        = `{"EvalID": "67890"}`
+	forcedEvaluation    = `{"ID": "67890", "JobID": "test/periodic-1"}`
+	childJobInfo        = `{"ID": "test/periodic-1", "Name": "test/periodic-1", "Type": "batch", "Version": 0}`
+	childAllocationsOK  = `[{"EvalID": "67890", "ID": "54321", "JobVersion": 0, "ClientStatus": "complete", "DesiredStatus": "run"}]`
+	childAllocationsBad = `[{"EvalID": "67890", "ID": "54321", "JobVersion": 0, "ClientStatus": "failed", "DesiredStatus": "run"}]`
+)
+
+func TestForceRun(t *testing.T) {
+	withMocks(func() {
+		Convey("periodic jobs are forced to run when asked", t, func() {
+			ctx := context.Background()
+			httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
+			httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test"), httpmock.NewStringResponder(200, periodicJobInfoSuccess))
+			httpmock.RegisterResponder("POST", fmt.Sprintf(forceURL, nomadURL, "test"), httpmock.NewStringResponder(200, forceSuccess))
+			httpmock.RegisterResponder("GET", fmt.Sprintf(evaluationURL, nomadURL, "67890"), httpmock.NewStringResponder(200, forcedEvaluation))
+			httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, childJobID), httpmock.NewStringResponder(200, childJobInfo))
+			dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+
+			Convey("and succeed once their child completes", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, childJobID), httpmock.NewStringResponder(200, childAllocationsOK))
+				err := dep.run(ctx, &engine.Message{ID: "1", Service: "test", ForceRun: true})
+				So(err, ShouldBeNil)
+				So(httpmock.GetCallCountInfo()["POST "+fmt.Sprintf(forceURL, nomadURL, "test")], ShouldEqual, 1)
+			})
+
+			Convey("and fail if their child fails", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, childJobID), httpmock.NewStringResponder(200, childAllocationsBad))
+				err := dep.run(ctx, &engine.Message{ID: "1", Service: "test", ForceRun: true})
+				So(err, ShouldResemble, &AbortedError{EvaluationID: "67890", CorrelationID: "1"})
+			})
+
+			Convey("but not otherwise", func() {
+				err := dep.run(ctx, &engine.Message{ID: "1", Service: "test"})
+				So(err, ShouldBeNil)
+				So(httpmock.GetCallCountInfo()["POST "+fmt.Sprintf(forceURL, nomadURL, "test")], ShouldEqual, 0)
+			})
+		})
+	})
+}
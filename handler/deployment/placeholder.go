@@ -0,0 +1,92 @@
+package deployment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// placeholderPattern matches bundle placeholders, e.g. "{{REVISION}}". Only
+// upper case names are matched so Nomad templates are left untouched.
+var placeholderPattern = regexp.MustCompile(`{{([A-Z][A-Z0-9_]*)}}`)
+
+// substitute replaces the placeholders in the bundle's job file. Values come
+// from the deployer config, then the bundle's environment values file, then the
+// message, with later sources taking precedence. Any unresolved placeholder is
+// an error.
+func (d *Deployment) substitute(ctx context.Context, msg *engine.Message) error {
+	jobPath := jobPath(msg)
+	b, err := os.ReadFile(jobPath)
+	if err != nil {
+		return err
+	}
+
+	values, err := d.placeholders(filepath.Dir(jobPath), msg.Placeholders)
+	if err != nil {
+		return err
+	}
+
+	unresolved := make(map[string]bool)
+	b = placeholderPattern.ReplaceAllFunc(b, func(p []byte) []byte {
+		name := string(placeholderPattern.FindSubmatch(p)[1])
+		v, ok := values[name]
+		if !ok {
+			unresolved[name] = true
+			return p
+		}
+		return []byte(v)
+	})
+	if len(unresolved) > 0 {
+		e := &PlaceholderError{File: jobPath}
+		for name := range unresolved {
+			e.Placeholders = append(e.Placeholders, name)
+		}
+		sort.Strings(e.Placeholders)
+		return e
+	}
+
+	log.Info(ctx, "substituted job placeholders", log.Data{"service": msg.Service})
+	return os.WriteFile(jobPath, b, 0600)
+}
+
+// placeholders returns the placeholder values by name.
+func (d *Deployment) placeholders(dir string, msgValues map[string]string) (map[string]string, error) {
+	values := make(map[string]string)
+	if len(d.ecrURL) > 0 {
+		values["ECR_URL"] = d.ecrURL
+	}
+	if len(d.deploymentsBucket) > 0 {
+		values["DEPLOYMENT_BUCKET"] = d.deploymentsBucket
+	}
+
+	if len(d.environment) > 0 {
+		b, err := os.ReadFile(filepath.Join(dir, d.environment+".values.json"))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			var fileValues map[string]interface{}
+			dec := json.NewDecoder(bytes.NewReader(b))
+			dec.UseNumber()
+			if err := dec.Decode(&fileValues); err != nil {
+				return nil, err
+			}
+			for k, v := range fileValues {
+				values[k] = fmt.Sprint(v)
+			}
+		}
+	}
+
+	for k, v := range msgValues {
+		values[k] = v
+	}
+	return values, nil
+}
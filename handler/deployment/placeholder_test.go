@@ -0,0 +1,64 @@
+package deployment
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var placeholderJob = `job "test" {
+  group "web" {
+    count = "{{TASK_COUNT}}"
+    task "test-web" {
+      config {
+        image = "{{ECR_URL}}:concourse-{{REVISION}}"
+      }
+      template {
+        data = "{{ key \"test\" }}{{.Data}}"
+      }
+    }
+  }
+}
+`
+
+func TestSubstitute(t *testing.T) {
+	Convey("placeholders are substituted as expected", t, func() {
+		ctx := context.Background()
+		dir := t.TempDir()
+		jobPath := filepath.Join(dir, "test.nomad")
+		So(os.WriteFile(jobPath, []byte(placeholderJob), 0600), ShouldBeNil)
+
+		dep := &Deployment{environment: "staging", ecrURL: "https://ecr"}
+
+		Convey("values are merged from config, the values file and the message", func() {
+			values := `{"TASK_COUNT": 2, "ECR_URL": "https://staging-ecr", "REVISION": "abc"}`
+			So(os.WriteFile(filepath.Join(dir, "staging.values.json"), []byte(values), 0600), ShouldBeNil)
+
+			err := dep.substitute(ctx, &engine.Message{Service: "test", Workspace: dir, Placeholders: map[string]string{"REVISION": "def"}})
+			So(err, ShouldBeNil)
+			b, _ := os.ReadFile(jobPath)
+			So(string(b), ShouldContainSubstring, `count = "2"`)
+			So(string(b), ShouldContainSubstring, `image = "https://staging-ecr:concourse-def"`)
+			So(string(b), ShouldContainSubstring, `{{ key \"test\" }}{{.Data}}`)
+		})
+
+		Convey("unresolved placeholders are an error", func() {
+			err := dep.substitute(ctx, &engine.Message{Service: "test", Workspace: dir})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "unresolved placeholders in job file")
+			So(err, ShouldResemble, &PlaceholderError{File: jobPath, Placeholders: []string{"REVISION", "TASK_COUNT"}})
+			b, _ := os.ReadFile(jobPath)
+			So(string(b), ShouldEqual, placeholderJob)
+		})
+
+		Convey("invalid values files are an error", func() {
+			So(os.WriteFile(filepath.Join(dir, "staging.values.json"), []byte("{"), 0600), ShouldBeNil)
+			err := dep.substitute(ctx, &engine.Message{Service: "test", Workspace: dir})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
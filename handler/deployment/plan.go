@@ -0,0 +1,171 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// PlanResult represents the result of planning a job without registering it.
+type PlanResult struct {
+	Diff           *api.JobDiff
+	Rendered       string
+	Updates        map[string]*api.DesiredUpdates   `json:",omitempty"`
+	FailedTGAllocs map[string]*api.AllocationMetric `json:",omitempty"`
+	Violations     []PlanViolation                  `json:",omitempty"`
+	Warnings       string                           `json:",omitempty"`
+}
+
+// PlanHandler handles plan messages that are delegated by the engine. The
+// bundle is planned with its diff, which is returned without registering the job.
+// TODO This function will be removed once the new queue has been implemented
+func (d *Deployment) PlanHandler(ctx context.Context, msg *engine.Message) (err error) {
+	defer func() { d.cleanupWorkspace(ctx, msg, err) }()
+	return d.handle(ctx, "PlanHandler", engineRequest(msg), func(ctx context.Context, d *Deployment) (interface{}, error) {
+		return result(d.planBundle(ctx, msg))
+	})
+}
+
+// planBundle fetches the message's bundle and plans its job with its diff.
+// TODO This function will be removed once the new queue has been implemented
+func (d *Deployment) planBundle(ctx context.Context, msg *engine.Message) (*PlanResult, error) {
+	if err := d.fetchBundle(ctx, msg); err != nil {
+		log.Error(ctx, "Deployment-planBundle, d.fetchBundle() error", err)
+		return nil, err
+	}
+	if err := d.substitute(ctx, msg); err != nil {
+		log.Error(ctx, "Deployment-planBundle, d.substitute() error", err)
+		return nil, err
+	}
+	j, err := d.jsonFormat(ctx, msg)
+	if err != nil {
+		log.Error(ctx, "Deployment-planBundle, d.jsonFormat() error", err)
+		return nil, err
+	}
+	var p payload
+	if err := json.Unmarshal(j, &p); err != nil {
+		log.Error(ctx, "Deployment-planBundle, json.Unmarshal() error", err)
+		return nil, err
+	}
+	return d.planDiff(ctx, msg.Service, p.Job)
+}
+
+// planDiff plans the job with diffs enabled and evaluates it against the plan
+// policy.
+func (d *Deployment) planDiff(ctx context.Context, jobID string, job *api.Job) (*PlanResult, error) {
+	log.Info(ctx, "planning job diff", log.Data{"service": jobID})
+
+	res, err := d.nomadClient.PlanJob(ctx, jobID, job, true)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PlanResult{
+		Diff:           res.Diff,
+		Rendered:       renderDiff(res.Diff),
+		FailedTGAllocs: res.FailedTGAllocs,
+		Warnings:       res.Warnings,
+	}
+	if res.Annotations != nil {
+		result.Updates = res.Annotations.DesiredTGUpdates
+	}
+	result.Violations = d.policy.Evaluate(result)
+	return result, nil
+}
+
+// renderDiff renders the job diff in a similar form to `nomad job plan`.
+func renderDiff(diff *api.JobDiff) string {
+	if diff == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%sJob: %q\n", diffPrefix(diff.Type), diff.ID)
+	renderFields(&b, diff.Fields, 1)
+	renderObjects(&b, diff.Objects, 1)
+	for _, tg := range diff.TaskGroups {
+		fmt.Fprintf(&b, "  %sTask Group: %q%s\n", diffPrefix(tg.Type), tg.Name, renderUpdates(tg.Updates))
+		renderFields(&b, tg.Fields, 2)
+		renderObjects(&b, tg.Objects, 2)
+		for _, task := range tg.Tasks {
+			if task.Type == string(structs.DiffTypeNone) {
+				continue
+			}
+			fmt.Fprintf(&b, "    %sTask: %q%s\n", diffPrefix(task.Type), task.Name, renderAnnotations(task.Annotations))
+			renderFields(&b, task.Fields, 3)
+			renderObjects(&b, task.Objects, 3)
+		}
+	}
+	return b.String()
+}
+
+func renderFields(b *strings.Builder, fields []*api.FieldDiff, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, f := range fields {
+		switch f.Type {
+		case string(structs.DiffTypeAdded):
+			fmt.Fprintf(b, "%s%s%s: %q%s\n", indent, diffPrefix(f.Type), f.Name, f.New, renderAnnotations(f.Annotations))
+		case string(structs.DiffTypeDeleted):
+			fmt.Fprintf(b, "%s%s%s: %q%s\n", indent, diffPrefix(f.Type), f.Name, f.Old, renderAnnotations(f.Annotations))
+		case string(structs.DiffTypeEdited):
+			fmt.Fprintf(b, "%s%s%s: %q => %q%s\n", indent, diffPrefix(f.Type), f.Name, f.Old, f.New, renderAnnotations(f.Annotations))
+		}
+	}
+}
+
+func renderObjects(b *strings.Builder, objects []*api.ObjectDiff, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, o := range objects {
+		if o.Type == string(structs.DiffTypeNone) {
+			continue
+		}
+		fmt.Fprintf(b, "%s%s%s {\n", indent, diffPrefix(o.Type), o.Name)
+		renderFields(b, o.Fields, depth+1)
+		renderObjects(b, o.Objects, depth+1)
+		fmt.Fprintf(b, "%s}\n", indent)
+	}
+}
+
+// renderUpdates renders the task group update counts in a stable order.
+func renderUpdates(updates map[string]uint64) string {
+	if len(updates) == 0 {
+		return ""
+	}
+	var names []string
+	for update := range updates {
+		names = append(names, update)
+	}
+	sort.Strings(names)
+
+	var counts []string
+	for _, update := range names {
+		counts = append(counts, fmt.Sprintf("%d %s", updates[update], update))
+	}
+	return " (" + strings.Join(counts, ", ") + ")"
+}
+
+func renderAnnotations(annotations []string) string {
+	if len(annotations) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(annotations, ", ") + ")"
+}
+
+func diffPrefix(diffType string) string {
+	switch diffType {
+	case string(structs.DiffTypeAdded):
+		return "+ "
+	case string(structs.DiffTypeDeleted):
+		return "- "
+	case string(structs.DiffTypeEdited):
+		return "+/- "
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/config"
+	"github.com/ONSdigital/dp-deployer/message"
+	"github.com/hashicorp/nomad/api"
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var planDiff = `{
+	"Diff": {"Type": "Edited", "ID": "test", "TaskGroups": [{
+		"Type": "Edited", "Name": "web", "Updates": {"create/destroy update": 2, "ignore": 1},
+		"Fields": [{"Type": "Edited", "Name": "Count", "Old": "2", "New": "3"}],
+		"Tasks": [{
+			"Type": "Edited", "Name": "test-web", "Annotations": ["forces create/destroy update"],
+			"Objects": [{"Type": "Edited", "Name": "Config", "Fields": [
+				{"Type": "Edited", "Name": "image", "Old": "test:1", "New": "test:2"},
+				{"Type": "None", "Name": "force_pull", "Old": "true", "New": "true"}
+			]}]
+		}, {"Type": "None", "Name": "test-sidecar"}]
+	}]},
+	"Annotations": {"DesiredTGUpdates": {"web": {"Ignore": 1, "DestructiveUpdate": 2}}}
+}`
+
+var renderedPlanDiff = `+/- Job: "test"
+  +/- Task Group: "web" (2 create/destroy update, 1 ignore)
+    +/- Count: "2" => "3"
+    +/- Task: "test-web" (forces create/destroy update)
+      +/- Config {
+        +/- image: "test:1" => "test:2"
+      }
+`
+
+func TestPlanDiff(t *testing.T) {
+	withMocks(func() {
+		Convey("plan diffs function as expected", t, func() {
+			ctx := context.Background()
+			var planRequest api.JobPlanRequest
+			httpmock.RegisterResponder("POST", fmt.Sprintf(planURL, nomadURL, "test"), func(req *http.Request) (*http.Response, error) {
+				b, _ := io.ReadAll(req.Body)
+				json.Unmarshal(b, &planRequest)
+				return httpmock.NewStringResponse(200, planDiff), nil
+			})
+			httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
+
+			Convey("api errors handled correctly", func() {
+				httpmock.RegisterResponder("POST", fmt.Sprintf(planURL, nomadURL, "test"), httpmock.NewStringResponder(500, "server error"))
+				dep := &Deployment{nomadClient: nomadClient}
+				res, err := dep.planDiff(ctx, "test", &api.Job{})
+				So(err, ShouldNotBeNil)
+				So(res, ShouldBeNil)
+			})
+
+			Convey("the diff is requested and rendered", func() {
+				dep := &Deployment{nomadClient: nomadClient}
+				res, err := dep.planDiff(ctx, "test", &api.Job{})
+				So(err, ShouldBeNil)
+				So(planRequest.Diff, ShouldBeTrue)
+				So(res.Diff.TaskGroups[0].Name, ShouldEqual, "web")
+				So(res.Updates["web"].DestructiveUpdate, ShouldEqual, 2)
+				So(res.Rendered, ShouldEqual, renderedPlanDiff)
+			})
+
+			Convey("plan messages are planned without being run", func() {
+				dep := &Deployment{nomadClient: nomadClient}
+				msg := &message.MessageSQS{Job: "test", Type: "plan", Web: &message.Groups{TaskCount: 1}, Healthcheck: &message.Healthcheck{}}
+				err := dep.NewHandler(ctx, config.Configuration{}, msg)
+				So(err, ShouldBeNil)
+				So(*planRequest.Job.Name, ShouldEqual, "test")
+				So(msg.Result.(*PlanResult).Rendered, ShouldEqual, renderedPlanDiff)
+				So(httpmock.GetCallCountInfo()["POST "+fmt.Sprintf(runURL, nomadURL)], ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -0,0 +1,142 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/ONSdigital/dp-deployer/config"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// Plan policy rules.
+const (
+	RuleMaxDestructiveUpdates = "max-destructive-updates"
+	RuleMaxStops              = "max-stops"
+	RuleMaxCPUGrowth          = "max-cpu-growth"
+	RuleMaxMemoryGrowth       = "max-memory-growth"
+	RuleJobTypeChange         = "job-type-change"
+)
+
+// growthRules are the resource growth rules by resource field name.
+var growthRules = map[string]string{"CPU": RuleMaxCPUGrowth, "MemoryMB": RuleMaxMemoryGrowth}
+
+// PlanViolation represents a plan policy rule that a plan has broken.
+type PlanViolation struct {
+	Rule   string
+	Target string `json:",omitempty"`
+	Limit  string `json:",omitempty"`
+	Actual string
+}
+
+// PlanPolicy represents the rules a plan must meet before a job is run. A zero
+// limit disables the rule.
+type PlanPolicy struct {
+	MaxDestructiveUpdates int
+	MaxStops              int
+	MaxResourceGrowth     float64
+	DenyTypeChange        bool
+}
+
+// NewPlanPolicy returns the plan policy from the configuration.
+func NewPlanPolicy(cfg *config.Configuration) PlanPolicy {
+	return PlanPolicy{
+		MaxDestructiveUpdates: cfg.PlanMaxDestructiveUpdates,
+		MaxStops:              cfg.PlanMaxStops,
+		MaxResourceGrowth:     cfg.PlanMaxResourceGrowth,
+		DenyTypeChange:        cfg.PlanDenyTypeChange,
+	}
+}
+
+// Evaluate returns the rules broken by the plan.
+func (p PlanPolicy) Evaluate(res *PlanResult) []PlanViolation {
+	var violations []PlanViolation
+
+	var destructive, stops uint64
+	for _, updates := range res.Updates {
+		destructive += updates.DestructiveUpdate
+		stops += updates.Stop
+	}
+	if p.MaxDestructiveUpdates > 0 && destructive > uint64(p.MaxDestructiveUpdates) {
+		violations = append(violations, PlanViolation{Rule: RuleMaxDestructiveUpdates, Limit: strconv.Itoa(p.MaxDestructiveUpdates), Actual: strconv.FormatUint(destructive, 10)})
+	}
+	if p.MaxStops > 0 && stops > uint64(p.MaxStops) {
+		violations = append(violations, PlanViolation{Rule: RuleMaxStops, Limit: strconv.Itoa(p.MaxStops), Actual: strconv.FormatUint(stops, 10)})
+	}
+	if res.Diff == nil {
+		return violations
+	}
+
+	if p.DenyTypeChange {
+		for _, f := range res.Diff.Fields {
+			if f.Name == "Type" && f.Type == string(structs.DiffTypeEdited) {
+				violations = append(violations, PlanViolation{Rule: RuleJobTypeChange, Target: res.Diff.ID, Actual: fmt.Sprintf("%s => %s", f.Old, f.New)})
+			}
+		}
+	}
+
+	if p.MaxResourceGrowth > 0 {
+		for _, tg := range res.Diff.TaskGroups {
+			for _, task := range tg.Tasks {
+				for _, o := range task.Objects {
+					if o.Name != "Resources" {
+						continue
+					}
+					for _, f := range o.Fields {
+						rule, ok := growthRules[f.Name]
+						if !ok || f.Type != string(structs.DiffTypeEdited) {
+							continue
+						}
+						if growth, ok := percentGrowth(f.Old, f.New); ok && growth > p.MaxResourceGrowth {
+							violations = append(violations, PlanViolation{
+								Rule:   rule,
+								Target: tg.Name + "/" + task.Name,
+								Limit:  fmt.Sprintf("%g%%", p.MaxResourceGrowth),
+								Actual: fmt.Sprintf("%g%%", growth),
+							})
+						}
+					}
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// checkPlan returns a PlanError if the plan has warnings, failed allocations or
+// breaks the plan policy. Policy violations are allowed if overridden.
+func (d *Deployment) checkPlan(ctx context.Context, service string, res *PlanResult, override bool) error {
+	if len(res.Warnings) > 0 {
+		return &PlanError{Service: service, Warnings: res.Warnings}
+	}
+	if res.FailedTGAllocs != nil {
+		j, err := json.Marshal(res.FailedTGAllocs)
+		if err != nil {
+			return err
+		}
+		return &PlanError{Errors: string(j), Service: service}
+	}
+
+	if len(res.Violations) == 0 {
+		return nil
+	}
+	if override {
+		log.Warn(ctx, "plan policy violations overridden", log.Data{"service": service, "violations": res.Violations})
+		return nil
+	}
+	return &PlanError{Service: service, Violations: res.Violations}
+}
+
+func percentGrowth(old, new string) (float64, bool) {
+	o, err := strconv.ParseFloat(old, 64)
+	if err != nil || o <= 0 {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(new, 64)
+	if err != nil {
+		return 0, false
+	}
+	return (n - o) / o * 100, true
+}
@@ -0,0 +1,80 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/hashicorp/nomad/api"
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var planPolicyDiff = `{
+	"Diff": {"Type": "Edited", "ID": "test",
+		"Fields": [{"Type": "Edited", "Name": "Type", "Old": "service", "New": "batch"}],
+		"TaskGroups": [{"Type": "Edited", "Name": "web", "Tasks": [{"Type": "Edited", "Name": "test-web",
+			"Objects": [{"Type": "Edited", "Name": "Resources", "Fields": [
+				{"Type": "Edited", "Name": "CPU", "Old": "100", "New": "110"},
+				{"Type": "Edited", "Name": "MemoryMB", "Old": "256", "New": "512"}
+			]}]
+		}]}]
+	},
+	"Annotations": {"DesiredTGUpdates": {"web": {"DestructiveUpdate": 3, "Stop": 1}}}
+}`
+
+func TestPlanPolicy(t *testing.T) {
+	Convey("plan policies are evaluated as expected", t, func() {
+		res := &PlanResult{
+			Updates: map[string]*api.DesiredUpdates{"web": {DestructiveUpdate: 3, Stop: 1}, "publishing": {DestructiveUpdate: 1}},
+			Diff: &api.JobDiff{ID: "test", TaskGroups: []*api.TaskGroupDiff{{Name: "web", Tasks: []*api.TaskDiff{{Name: "test-web", Objects: []*api.ObjectDiff{{
+				Name: "Resources",
+				Fields: []*api.FieldDiff{
+					{Type: "Edited", Name: "CPU", Old: "100", New: "110"},
+					{Type: "Edited", Name: "MemoryMB", Old: "256", New: "512"},
+				},
+			}}}}}}},
+		}
+
+		Convey("disabled rules are not broken", func() {
+			So(PlanPolicy{}.Evaluate(res), ShouldBeEmpty)
+		})
+
+		Convey("update limits are enforced across task groups", func() {
+			violations := PlanPolicy{MaxDestructiveUpdates: 3, MaxStops: 1}.Evaluate(res)
+			So(violations, ShouldResemble, []PlanViolation{{Rule: RuleMaxDestructiveUpdates, Limit: "3", Actual: "4"}})
+		})
+
+		Convey("resource growth is limited per task", func() {
+			violations := PlanPolicy{MaxResourceGrowth: 50}.Evaluate(res)
+			So(violations, ShouldResemble, []PlanViolation{{Rule: RuleMaxMemoryGrowth, Target: "web/test-web", Limit: "50%", Actual: "100%"}})
+		})
+
+		Convey("job type changes are denied", func() {
+			res.Diff.Fields = []*api.FieldDiff{{Type: "Edited", Name: "Type", Old: "service", New: "batch"}}
+			violations := PlanPolicy{DenyTypeChange: true}.Evaluate(res)
+			So(violations, ShouldResemble, []PlanViolation{{Rule: RuleJobTypeChange, Target: "test", Actual: "service => batch"}})
+		})
+	})
+
+	withMocks(func() {
+		Convey("plan policy violations are enforced before running", t, func() {
+			ctx := context.Background()
+			httpmock.RegisterResponder("POST", fmt.Sprintf(planURL, nomadURL, "test"), httpmock.NewStringResponder(200, planPolicyDiff))
+			dep := &Deployment{nomadClient: nomadClient, policy: PlanPolicy{MaxDestructiveUpdates: 2, MaxResourceGrowth: 50, DenyTypeChange: true}}
+
+			Convey("violations are returned as a plan error", func() {
+				err := dep.plan(ctx, &engine.Message{Service: "test"})
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "plan violates deployment policy")
+				So(err.(*PlanError).Violations, ShouldHaveLength, 3)
+			})
+
+			Convey("violations are allowed when overridden", func() {
+				err := dep.plan(ctx, &engine.Message{Service: "test", Override: true})
+				So(err, ShouldBeNil)
+			})
+		})
+	})
+}
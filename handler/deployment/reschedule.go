@@ -0,0 +1,83 @@
+package deployment
+
+import (
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// batchAllocations returns the allocations placed by the evaluation, along with
+// the allocations Nomad has rescheduled them to.
+func batchAllocations(allocations []api.AllocationListStub, evaluationID string) []api.AllocationListStub {
+	placed := make(map[string]bool)
+	for _, allocation := range allocations {
+		if allocation.EvalID == evaluationID {
+			placed[allocation.ID] = true
+		}
+	}
+	for found := true; found; {
+		found = false
+		for _, allocation := range allocations {
+			if !placed[allocation.ID] && placed[previousAllocation(allocation)] {
+				placed[allocation.ID], found = true, true
+			}
+		}
+	}
+
+	var batch []api.AllocationListStub
+	for _, allocation := range allocations {
+		if placed[allocation.ID] {
+			batch = append(batch, allocation)
+		}
+	}
+	return batch
+}
+
+// previousAllocation returns the ID of the allocation this one was rescheduled
+// from, if any.
+func previousAllocation(allocation api.AllocationListStub) string {
+	if allocation.RescheduleTracker == nil || len(allocation.RescheduleTracker.Events) == 0 {
+		return ""
+	}
+	return allocation.RescheduleTracker.Events[len(allocation.RescheduleTracker.Events)-1].PrevAllocID
+}
+
+// terminalFailure reports whether the allocation failed or was lost and Nomad
+// will not reschedule it, either because its reschedule policy does not allow
+// it or because the attempts within its interval have been used up.
+func terminalFailure(allocation api.AllocationListStub, job *api.Job) bool {
+	if allocation.ClientStatus != structs.AllocClientStatusFailed && allocation.ClientStatus != structs.AllocClientStatusLost {
+		return false
+	}
+	if len(allocation.NextAllocation) > 0 || len(allocation.FollowupEvalID) > 0 {
+		return false
+	}
+
+	var policy *api.ReschedulePolicy
+	for _, tg := range job.TaskGroups {
+		if tg.Name != nil && *tg.Name == allocation.TaskGroup {
+			policy = tg.ReschedulePolicy
+		}
+	}
+	if policy == nil {
+		return true
+	}
+	if policy.Unlimited != nil && *policy.Unlimited {
+		return false
+	}
+
+	attempts := 0
+	if policy.Attempts != nil {
+		attempts = *policy.Attempts
+	}
+	used := 0
+	if allocation.RescheduleTracker != nil {
+		for _, event := range allocation.RescheduleTracker.Events {
+			if policy.Interval == nil || *policy.Interval == 0 || time.Since(time.Unix(0, event.RescheduleTime)) <= *policy.Interval {
+				used++
+			}
+		}
+	}
+	return used >= attempts
+}
@@ -0,0 +1,70 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	batchAllocationFailed      = `{"EvalID": "12345", "ID": "a1", "TaskGroup": "run", "JobVersion": 2, "ClientStatus": "failed", "DesiredStatus": "run"}`
+	batchAllocationRescheduled = `{"EvalID": "12345", "ID": "a1", "TaskGroup": "run", "JobVersion": 2, "ClientStatus": "failed", "DesiredStatus": "run", "NextAllocation": "a2"}`
+	batchAllocationReplacement = `{"EvalID": "23456", "ID": "a2", "TaskGroup": "run", "JobVersion": 2, "ClientStatus": "complete", "DesiredStatus": "run", "RescheduleTracker": {"Events": [{"PrevAllocID": "a1"}]}}`
+	batchAllocationOldFailed   = `{"EvalID": "12345", "ID": "a0", "TaskGroup": "run", "JobVersion": 1, "ClientStatus": "failed", "DesiredStatus": "run"}`
+	batchAllocationComplete    = `{"EvalID": "12345", "ID": "a1", "TaskGroup": "run", "JobVersion": 2, "ClientStatus": "complete", "DesiredStatus": "run"}`
+
+	batchJobReschedulable = `{"ID": "test", "Type": "batch", "Version": 2, "TaskGroups": [{"Name": "run", "ReschedulePolicy": {"Attempts": 1, "Interval": 86400000000000}}]}`
+	batchJobNoReschedule  = `{"ID": "test", "Type": "batch", "Version": 2, "TaskGroups": [{"Name": "run", "ReschedulePolicy": {"Attempts": 0, "Interval": 86400000000000}}]}`
+)
+
+func TestBatchReschedules(t *testing.T) {
+	withMocks(func() {
+		Convey("batch allocations are only failed once nomad will not reschedule them", t, func() {
+			ctx := context.Background()
+			dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+			checks := 0
+			allocations := func(responses ...string) {
+				checks = 0
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, "test"), func(req *http.Request) (*http.Response, error) {
+					r := responses[len(responses)-1]
+					if checks < len(responses) {
+						r = responses[checks]
+					}
+					checks++
+					return httpmock.NewStringResponse(200, r), nil
+				})
+			}
+
+			Convey("failed allocations of an older version do not abort", func() {
+				allocations(`[`+batchAllocationOldFailed+`, `+batchAllocationComplete+`]`, `[`+batchAllocationComplete+`]`)
+				So(dep.successCheckByAllocationsBatch(ctx, "1", "12345", "test", 2), ShouldBeNil)
+				So(checks, ShouldEqual, 2)
+			})
+
+			Convey("rescheduled allocations are followed to their replacement", func() {
+				allocations(`[` + batchAllocationRescheduled + `, ` + batchAllocationReplacement + `]`)
+				So(dep.successCheckByAllocationsBatch(ctx, "1", "12345", "test", 2), ShouldBeNil)
+			})
+
+			Convey("failed allocations with reschedule attempts left do not abort", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test"), httpmock.NewStringResponder(200, batchJobReschedulable))
+				allocations(`[`+batchAllocationFailed+`]`, `[`+batchAllocationRescheduled+`, `+batchAllocationReplacement+`]`)
+				So(dep.successCheckByAllocationsBatch(ctx, "1", "12345", "test", 2), ShouldBeNil)
+				So(checks, ShouldEqual, 2)
+			})
+
+			Convey("failed allocations without reschedule attempts left abort", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test"), httpmock.NewStringResponder(200, batchJobNoReschedule))
+				allocations(`[` + batchAllocationFailed + `]`)
+				err := dep.successCheckByAllocationsBatch(ctx, "1", "12345", "test", 2)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
+				So(checks, ShouldEqual, 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,145 @@
+package deployment
+
+import (
+	"context"
+	"time"
+
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// RestartDependents restarts the running allocations of every job with a vault
+// policy matching the given secret path, one allocation at a time. Each
+// allocation must be running again before the next is restarted. The IDs of the
+// restarted allocations are returned.
+func (d *Deployment) RestartDependents(ctx context.Context, path string) ([]string, error) {
+	jobs, err := d.nomadClient.ListJobs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var restarted []string
+	for _, stub := range jobs {
+		if stub.Status != structs.JobStatusRunning || stub.Type == api.JobTypeBatch {
+			continue
+		}
+
+		job, err := d.nomadClient.GetJob(ctx, stub.ID)
+		if err != nil {
+			return restarted, err
+		}
+		if !usesPolicy(job, path) {
+			continue
+		}
+
+		log.Info(ctx, "restarting allocations for secret change", log.Data{"job": stub.ID, "path": path})
+		allocations, err := d.runningAllocations(ctx, stub.ID)
+		if err != nil {
+			return restarted, err
+		}
+		for _, allocation := range allocations {
+			if err := d.restartAllocation(ctx, stub.ID, allocation.ID); err != nil {
+				return restarted, err
+			}
+			restarted = append(restarted, allocation.ID)
+		}
+	}
+	return restarted, nil
+}
+
+// usesPolicy reports whether any task in the job is granted the vault policy
+// for the path, either directly or suffixed with its task group name.
+func usesPolicy(job *api.Job, path string) bool {
+	for _, tg := range job.TaskGroups {
+		for _, task := range tg.Tasks {
+			if task.Vault == nil {
+				continue
+			}
+			for _, policy := range task.Vault.Policies {
+				if policy == path || (tg.Name != nil && policy == path+"-"+*tg.Name) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (d *Deployment) runningAllocations(ctx context.Context, jobID string) ([]api.AllocationListStub, error) {
+	allocations, err := d.nomadClient.ListAllocations(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	var running []api.AllocationListStub
+	for _, allocation := range allocations {
+		if allocation.DesiredStatus == structs.AllocDesiredStatusRun && allocation.ClientStatus == structs.AllocClientStatusRunning {
+			running = append(running, allocation)
+		}
+	}
+	return running, nil
+}
+
+// restartAllocation restarts all tasks in the allocation and waits until each
+// task has restarted and is running again.
+func (d *Deployment) restartAllocation(ctx context.Context, jobID, allocationID string) error {
+	before, err := d.nomadClient.GetAllocation(ctx, allocationID)
+	if err != nil {
+		return err
+	}
+	if err := d.nomadClient.RestartAllocation(ctx, allocationID); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(time.Second * 1)
+	defer ticker.Stop()
+	timeout := time.NewTimer(d.timeout)
+	defer timeout.Stop()
+	minLogData := log.Data{"job": jobID, "allocation": allocationID}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Warn(ctx, "bailing on allocation restart", minLogData)
+			return &RestartError{AllocationID: allocationID, JobID: jobID}
+		case <-timeout.C:
+			return &TimeoutError{Action: "restart"}
+		case <-ticker.C:
+			after, err := d.nomadClient.GetAllocation(ctx, allocationID)
+			if err != nil {
+				return err
+			}
+			if after.ClientStatus != structs.AllocClientStatusRunning && after.ClientStatus != structs.AllocClientStatusPending {
+				log.Warn(ctx, "allocation failed after restart", minLogData)
+				return &RestartError{AllocationID: allocationID, JobID: jobID}
+			}
+			if restartedAndRunning(before, after) {
+				log.Info(ctx, "allocation restarted", minLogData)
+				return nil
+			}
+			log.Info(ctx, "allocation restart incomplete - will re-test", minLogData)
+		}
+	}
+}
+
+// restartedAndRunning returns whether every task of the allocation has been
+// restarted and is running. Lifecycle tasks that have finished successfully are
+// not restarted, so they are skipped.
+func restartedAndRunning(before, after *api.Allocation) bool {
+	if after.ClientStatus != structs.AllocClientStatusRunning || len(after.TaskStates) == 0 {
+		return false
+	}
+	for name, state := range after.TaskStates {
+		if state.State == structs.TaskStateDead && !state.Failed {
+			continue
+		}
+		if state.State != structs.TaskStateRunning {
+			return false
+		}
+		if previous, ok := before.TaskStates[name]; ok && state.Restarts <= previous.Restarts {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	jobsList = `[{"ID": "test", "Type": "service", "Status": "running"}, {"ID": "other", "Type": "service", "Status": "running"}, {"ID": "stopped", "Type": "service", "Status": "dead"}]`
+
+	dependentJobInfo = `{"ID": "test", "Name": "test", "Type": "service", "TaskGroups": [{"Name": "web", "Tasks": [{"Name": "test-web", "Vault": {"Policies": ["test-web"]}}]}]}`
+	otherJobInfo     = `{"ID": "other", "Name": "other", "Type": "service", "TaskGroups": [{"Name": "web", "Tasks": [{"Name": "other-web", "Vault": {"Policies": ["other-web"]}}]}]}`
+
+	allocationBeforeRestart   = `{"ID": "54321", "ClientStatus": "running", "TaskStates": {"test-web": {"State": "running", "Restarts": 0}}}`
+	allocationAfterRestart    = `{"ID": "54321", "ClientStatus": "running", "TaskStates": {"test-web": {"State": "running", "Restarts": 1}}}`
+	allocationBeforeLifecycle = `{"ID": "54321", "ClientStatus": "running", "TaskStates": {"test-init": {"State": "dead", "Failed": false, "Restarts": 0}, "test-web": {"State": "running", "Restarts": 0}}}`
+	allocationAfterLifecycle  = `{"ID": "54321", "ClientStatus": "running", "TaskStates": {"test-init": {"State": "dead", "Failed": false, "Restarts": 0}, "test-web": {"State": "running", "Restarts": 1}}}`
+	allocationFailedRestart   = `{"ID": "54321", "ClientStatus": "failed", "TaskStates": {"test-web": {"State": "dead", "Failed": true, "Restarts": 1}}}`
+)
+
+func TestRestartDependents(t *testing.T) {
+	withMocks(func() {
+		Convey("restarting dependent jobs functions as expected", t, func() {
+			ctx := context.Background()
+			httpmock.RegisterResponder("GET", fmt.Sprintf(jobsURL, nomadURL), httpmock.NewStringResponder(200, jobsList))
+			httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test"), httpmock.NewStringResponder(200, dependentJobInfo))
+			httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "other"), httpmock.NewStringResponder(200, otherJobInfo))
+			httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, "test"), httpmock.NewStringResponder(200, allocationsStopIsStopped))
+			httpmock.RegisterResponder("POST", fmt.Sprintf(restartURL, nomadURL, "54321"), httpmock.NewStringResponder(200, `{}`))
+
+			Convey("jobs list api errors handled correctly", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(jobsURL, nomadURL), httpmock.NewStringResponder(500, "server error"))
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				restarted, err := dep.RestartDependents(ctx, "test")
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unexpected response from client")
+				So(restarted, ShouldBeEmpty)
+			})
+
+			Convey("only running allocations of jobs using the policy are restarted", func() {
+				calls := 0
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationURL, nomadURL, "54321"), func(req *http.Request) (*http.Response, error) {
+					calls++
+					if calls == 1 {
+						return httpmock.NewStringResponse(200, allocationBeforeRestart), nil
+					}
+					return httpmock.NewStringResponse(200, allocationAfterRestart), nil
+				})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				restarted, err := dep.RestartDependents(ctx, "test")
+				So(err, ShouldBeNil)
+				So(restarted, ShouldResemble, []string{"54321"})
+				So(httpmock.GetCallCountInfo()["POST "+fmt.Sprintf(restartURL, nomadURL, "54321")], ShouldEqual, 1)
+				So(httpmock.GetCallCountInfo()["GET "+fmt.Sprintf(allocationsURL, nomadURL, "other")], ShouldEqual, 0)
+			})
+
+			Convey("finished lifecycle tasks are not waited on", func() {
+				calls := 0
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationURL, nomadURL, "54321"), func(req *http.Request) (*http.Response, error) {
+					calls++
+					if calls == 1 {
+						return httpmock.NewStringResponse(200, allocationBeforeLifecycle), nil
+					}
+					return httpmock.NewStringResponse(200, allocationAfterLifecycle), nil
+				})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				restarted, err := dep.RestartDependents(ctx, "test")
+				So(err, ShouldBeNil)
+				So(restarted, ShouldResemble, []string{"54321"})
+			})
+
+			Convey("allocations failing after a restart are handled correctly", func() {
+				calls := 0
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationURL, nomadURL, "54321"), func(req *http.Request) (*http.Response, error) {
+					calls++
+					if calls == 1 {
+						return httpmock.NewStringResponse(200, allocationBeforeRestart), nil
+					}
+					return httpmock.NewStringResponse(200, allocationFailedRestart), nil
+				})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient}
+				restarted, err := dep.RestartDependents(ctx, "test")
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "allocation failed to restart")
+				So(restarted, ShouldBeEmpty)
+			})
+
+			Convey("restart timeouts handled correctly", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationURL, nomadURL, "54321"), httpmock.NewStringResponder(200, allocationBeforeRestart))
+				dep := &Deployment{timeout: shortTimeout, nomadClient: nomadClient}
+				_, err := dep.RestartDependents(ctx, "test")
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "timed out waiting for action to complete")
+			})
+		})
+	})
+}
@@ -0,0 +1,159 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/hashicorp/nomad/api"
+)
+
+var errNoStableVersion = errors.New("no stable version to roll back to")
+
+// Rollback policies.
+const (
+	// RollbackNone never rolls back a failed deployment.
+	RollbackNone = "none"
+	// RollbackAuto rolls back failed deployments unless Nomad will auto revert
+	// them itself.
+	RollbackAuto = "auto"
+	// RollbackAlways rolls back every failed deployment.
+	RollbackAlways = "always"
+)
+
+// rollback reverts the job to its last stable version if the deployment failed
+// or was cancelled and the rollback policy allows it, waiting for the restored
+// version to be healthy. The original error is returned if no rollback takes
+// place.
+func (d *Deployment) rollback(ctx context.Context, correlationID, jobID string, cause error) error {
+	switch cause.(type) {
+	case *AbortedError, *TimeoutError:
+	default:
+		return cause
+	}
+	if d.rollbackPolicy != RollbackAuto && d.rollbackPolicy != RollbackAlways {
+		return cause
+	}
+	// A cancelled message is rolled back like a failed deployment, but the job
+	// is left alone when the deployer is shutting down.
+	if ctx.Err() != nil {
+		if !isCancelled(ctx) {
+			return cause
+		}
+		cause = context.Cause(ctx)
+	}
+	ctx, stop := rollbackContext(ctx)
+	defer stop()
+
+	jobInfo, err := d.nomadClient.GetJob(ctx, jobID)
+	if err != nil {
+		log.Error(ctx, "Deployment-rollback, d.nomadClient.GetJob() error", err)
+		return cause
+	}
+	if d.rollbackPolicy == RollbackAuto && autoReverts(jobInfo) {
+		log.Info(ctx, "leaving rollback to nomad auto revert", log.Data{"job": jobID})
+		return cause
+	}
+
+	failed := *jobInfo.Version
+	restored, err := d.revert(ctx, correlationID, jobID, jobInfo)
+	if err != nil {
+		log.Error(ctx, "Deployment-rollback, d.revert() error", err, log.Data{"job": jobID, "failed_version": failed})
+		return cause
+	}
+	log.Info(ctx, "deployment rolled back", log.Data{"job": jobID, "failed_version": failed, "restored_version": restored})
+	return &RollbackError{Cause: cause.Error(), FailedVersion: failed, JobID: jobID, RestoredVersion: restored}
+}
+
+// rollbackContext returns a context for rolling back the job that outlives the
+// message being cancelled, but is still done if the deployer shuts down.
+func rollbackContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	rctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		if !isCancelled(ctx) {
+			cancel()
+		}
+	})
+	return rctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// revert reverts the job to the last stable version before the current one and
+// returns the restored version once it is healthy.
+func (d *Deployment) revert(ctx context.Context, correlationID, jobID string, jobInfo *api.Job) (uint64, error) {
+	versions, err := d.nomadClient.GetJobVersions(ctx, jobID)
+	if err != nil {
+		return 0, err
+	}
+	target, ok := lastStableVersion(versions.Versions, *jobInfo.Version, *jobInfo.Type)
+	if !ok {
+		return 0, errNoStableVersion
+	}
+	if err := d.revertTo(ctx, correlationID, jobID, jobInfo, target); err != nil {
+		return 0, err
+	}
+	return target, nil
+}
+
+// revertTo reverts the job to the target version and waits for it to be healthy.
+func (d *Deployment) revertTo(ctx context.Context, correlationID, jobID string, jobInfo *api.Job, target uint64) error {
+	res, err := d.nomadClient.RevertJob(ctx, &api.JobRevertRequest{JobID: jobID, JobVersion: target, EnforcePriorVersion: jobInfo.Version})
+	if err != nil {
+		return err
+	}
+
+	// Reverting registers the old job spec as a new version, so monitor that.
+	reverted, err := d.nomadClient.GetJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	switch *jobInfo.Type {
+	case api.JobTypeSystem:
+		err = d.successCheckByAllocations(ctx, correlationID, res.EvalID, jobID, *reverted.Version)
+	case api.JobTypeBatch:
+		err = d.successCheckByAllocationsBatch(ctx, correlationID, res.EvalID, jobID, *reverted.Version)
+	default:
+		err = d.successCheckByDeployment(ctx, correlationID, res.EvalID, jobID, res.JobModifyIndex)
+	}
+	return err
+}
+
+// lastStableVersion returns the newest version before the failed one that
+// Nomad marked as stable. Only service deployments are marked stable, so the
+// previous version is used for other job types.
+func lastStableVersion(versions []*api.Job, failed uint64, jobType string) (uint64, bool) {
+	var target uint64
+	found := false
+	for _, v := range versions {
+		if v.Version == nil || *v.Version >= failed {
+			continue
+		}
+		if jobType == api.JobTypeService && (v.Stable == nil || !*v.Stable) {
+			continue
+		}
+		if !found || *v.Version > target {
+			target, found = *v.Version, true
+		}
+	}
+	return target, found
+}
+
+// autoReverts reports whether every task group of a service job is configured
+// to auto revert, in which case Nomad will roll it back itself.
+func autoReverts(job *api.Job) bool {
+	if job.Type == nil || *job.Type != api.JobTypeService || len(job.TaskGroups) == 0 {
+		return false
+	}
+	for _, tg := range job.TaskGroups {
+		autoRevert := job.Update != nil && job.Update.AutoRevert != nil && *job.Update.AutoRevert
+		if tg.Update != nil && tg.Update.AutoRevert != nil {
+			autoRevert = *tg.Update.AutoRevert
+		}
+		if !autoRevert {
+			return false
+		}
+	}
+	return true
+}
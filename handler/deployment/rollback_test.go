@@ -0,0 +1,135 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/hashicorp/nomad/api"
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	serviceJobInfoNoAutoRevert = `{"ID": "test", "Name": "test", "Type": "service", "Version": 2, "TaskGroups": [{"Name": "web", "Update": {"AutoRevert": false}}]}`
+	serviceJobInfoAutoRevert   = `{"ID": "test", "Name": "test", "Type": "service", "Version": 2, "TaskGroups": [{"Name": "web", "Update": {"AutoRevert": true}}]}`
+	serviceJobVersions         = `{"Versions": [{"Version": 2, "Stable": false}, {"Version": 1, "Stable": false}, {"Version": 0, "Stable": true}]}`
+	systemJobInfoReverted      = `{"ID": "test", "Name": "test", "Type": "system", "Version": 3}`
+	systemJobVersions          = `{"Versions": [{"Version": 2}, {"Version": 1}, {"Version": 0}]}`
+
+	revertSuccess              = `{"EvalID": "23456", "JobModifyIndex": 100}`
+	deploymentRevertSuccess    = `[` + otherDeployment + `,{"JobSpecModifyIndex": 99, "ID": "54321", "Status": "failed"},{"JobSpecModifyIndex": 100, "Status": "successful"}]`
+	allocationsRevertedVersion = `[{"ID": "54321", "JobVersion": 3, "ClientStatus": "running", "DesiredStatus": "run"}]`
+)
+
+func TestRollback(t *testing.T) {
+	withMocks(func() {
+		Convey("rollback functions as expected", t, func() {
+			ctx := context.Background()
+			httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL), httpmock.NewStringResponder(200, jobSuccess))
+
+			var revertRequest api.JobRevertRequest
+			reverted := false
+			httpmock.RegisterResponder("POST", fmt.Sprintf(revertURL, nomadURL, "test"), func(req *http.Request) (*http.Response, error) {
+				b, _ := io.ReadAll(req.Body)
+				json.Unmarshal(b, &revertRequest)
+				reverted = true
+				return httpmock.NewStringResponse(200, revertSuccess), nil
+			})
+
+			Convey("failed service deployments are reverted to the last stable version", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test"), httpmock.NewStringResponder(200, serviceJobInfoNoAutoRevert))
+				httpmock.RegisterResponder("GET", fmt.Sprintf(versionsURL, nomadURL, "test"), httpmock.NewStringResponder(200, serviceJobVersions))
+				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, "test"), func(req *http.Request) (*http.Response, error) {
+					if reverted {
+						return httpmock.NewStringResponse(200, deploymentRevertSuccess), nil
+					}
+					return httpmock.NewStringResponse(200, deploymentError), nil
+				})
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient, rollbackPolicy: RollbackAuto}
+				err := dep.run(ctx, &engine.Message{ID: "1", Service: "test"})
+				So(err, ShouldResemble, &RollbackError{Cause: "aborted monitoring deployment", FailedVersion: 2, JobID: "test", RestoredVersion: 0})
+				So(revertRequest.JobVersion, ShouldEqual, 0)
+				So(*revertRequest.EnforcePriorVersion, ShouldEqual, 2)
+			})
+
+			Convey("timed out system deployments are reverted to the previous version", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test"), func(req *http.Request) (*http.Response, error) {
+					if reverted {
+						return httpmock.NewStringResponse(200, systemJobInfoReverted), nil
+					}
+					return httpmock.NewStringResponse(200, systemJobInfoSuccess), nil
+				})
+				httpmock.RegisterResponder("GET", fmt.Sprintf(versionsURL, nomadURL, "test"), httpmock.NewStringResponder(200, systemJobVersions))
+				httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, "test"), func(req *http.Request) (*http.Response, error) {
+					if reverted {
+						return httpmock.NewStringResponse(200, allocationsRevertedVersion), nil
+					}
+					return httpmock.NewStringResponse(200, allocationsPending), nil
+				})
+				dep := &Deployment{timeout: shortTimeout, nomadClient: nomadClient, rollbackPolicy: RollbackAlways}
+				err := dep.run(ctx, &engine.Message{ID: "1", Service: "test"})
+				So(err, ShouldResemble, &RollbackError{Cause: "timed out waiting for action to complete", FailedVersion: 2, JobID: "test", RestoredVersion: 1})
+				So(revertRequest.JobVersion, ShouldEqual, 1)
+			})
+
+			Convey("cancelled deployments are reverted", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test"), httpmock.NewStringResponder(200, serviceJobInfoNoAutoRevert))
+				httpmock.RegisterResponder("GET", fmt.Sprintf(versionsURL, nomadURL, "test"), httpmock.NewStringResponder(200, serviceJobVersions))
+				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, "test"), httpmock.NewStringResponder(200, deploymentRevertSuccess))
+				ctx, cancel := context.WithCancelCause(ctx)
+				cancel(&CancelledError{MessageID: "2"})
+
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient, rollbackPolicy: RollbackAlways}
+				err := dep.rollback(ctx, "1", "test", &AbortedError{EvaluationID: "12345", CorrelationID: "1"})
+				So(err, ShouldResemble, &RollbackError{Cause: "cancelled", FailedVersion: 2, JobID: "test", RestoredVersion: 0})
+				So(cancelled(ctx, err), ShouldEqual, err)
+				So(reverted, ShouldBeTrue)
+			})
+
+			Convey("deployments are not reverted when the deployer shuts down", func() {
+				ctx, cancel := context.WithCancel(ctx)
+				cancel()
+				calls := httpmock.GetTotalCallCount()
+
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient, rollbackPolicy: RollbackAlways}
+				cause := &AbortedError{EvaluationID: "12345", CorrelationID: "1"}
+				So(dep.rollback(ctx, "1", "test", cause), ShouldEqual, cause)
+				So(reverted, ShouldBeFalse)
+				So(httpmock.GetTotalCallCount(), ShouldEqual, calls)
+			})
+
+			Convey("auto reverting service jobs are left to nomad", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test"), httpmock.NewStringResponder(200, serviceJobInfoAutoRevert))
+				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, "test"), httpmock.NewStringResponder(200, deploymentError))
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient, rollbackPolicy: RollbackAuto}
+				err := dep.run(ctx, &engine.Message{ID: "1", Service: "test"})
+				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
+				So(reverted, ShouldBeFalse)
+			})
+
+			Convey("failed deployments are not reverted without a rollback policy", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test"), httpmock.NewStringResponder(200, serviceJobInfoNoAutoRevert))
+				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, "test"), httpmock.NewStringResponder(200, deploymentError))
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient, rollbackPolicy: RollbackNone}
+				err := dep.run(ctx, &engine.Message{ID: "1", Service: "test"})
+				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
+				So(reverted, ShouldBeFalse)
+			})
+
+			Convey("the original error is returned when there is no stable version", func() {
+				httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test"), httpmock.NewStringResponder(200, serviceJobInfoNoAutoRevert))
+				httpmock.RegisterResponder("GET", fmt.Sprintf(versionsURL, nomadURL, "test"), httpmock.NewStringResponder(200, `{"Versions": [{"Version": 2}, {"Version": 1}]}`))
+				httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, "test"), httpmock.NewStringResponder(200, deploymentError))
+				dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient, rollbackPolicy: RollbackAlways}
+				err := dep.run(ctx, &engine.Message{ID: "1", Service: "test"})
+				So(err.Error(), ShouldEqual, "aborted monitoring deployment")
+				So(reverted, ShouldBeFalse)
+			})
+		})
+	})
+}
@@ -0,0 +1,195 @@
+package deployment
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/message"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/hashicorp/nomad/api"
+)
+
+// RestartResult represents the result of a rolling restart of a job, with the
+// IDs of the allocations restarted in each batch.
+type RestartResult struct {
+	Job     string
+	Batches [][]string
+}
+
+// RestartHandler handles restart messages that are delegated by the engine.
+// The running allocations of the service's job are restarted in batches.
+// TODO This function will be removed once the new queue has been implemented
+func (d *Deployment) RestartHandler(ctx context.Context, msg *engine.Message) error {
+	return d.handle(ctx, "RestartHandler", engineRequest(msg, msg.Service), func(ctx context.Context, d *Deployment) (interface{}, error) {
+		return result(d.rollingRestart(ctx, msg.Service))
+	})
+}
+
+func (d *Deployment) restartNew(ctx context.Context, msg *message.MessageSQS) error {
+	return d.handle(ctx, "restartNew", sqsRequest(msg, msg.Job), func(ctx context.Context, d *Deployment) (interface{}, error) {
+		return result(d.rollingRestart(ctx, msg.Job))
+	})
+}
+
+// rollingRestart restarts the job's running allocations in batches, waiting for
+// each batch to be running and passing its service checks, then pausing, before
+// the next batch is restarted. The batches restarted so far are returned with
+// any error.
+func (d *Deployment) rollingRestart(ctx context.Context, jobID string) (*RestartResult, error) {
+	allocations, err := d.runningAllocations(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	size := d.restartBatchSize
+	if size < 1 {
+		size = 1
+	}
+	result := &RestartResult{Job: jobID}
+	for start := 0; start < len(allocations); start += size {
+		if start > 0 {
+			if err := d.pause(ctx, jobID); err != nil {
+				return result, err
+			}
+		}
+
+		var batch []string
+		for i := start; i < start+size && i < len(allocations); i++ {
+			batch = append(batch, allocations[i].ID)
+		}
+		logData := log.Data{"job": jobID, "batch": len(result.Batches) + 1, "allocations": batch}
+		log.Info(ctx, "restarting allocation batch", logData)
+
+		result.Batches = append(result.Batches, batch)
+		if err := d.restartBatch(ctx, jobID, batch); err != nil {
+			return result, err
+		}
+		if err := d.waitForChecks(ctx, jobID, batch); err != nil {
+			return result, err
+		}
+		log.Info(ctx, "allocation batch restarted", logData)
+	}
+	return result, nil
+}
+
+// restartBatch restarts the allocations at the same time and waits until they
+// are all running again.
+func (d *Deployment) restartBatch(ctx context.Context, jobID string, batch []string) error {
+	errs := make([]error, len(batch))
+	var wg sync.WaitGroup
+	for i, allocationID := range batch {
+		wg.Add(1)
+		go func(i int, allocationID string) {
+			defer wg.Done()
+			errs[i] = d.restartAllocation(ctx, jobID, allocationID)
+		}(i, allocationID)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForChecks waits until none of the allocations have failing service
+// checks. Errors reading the checks are returned rather than treated as passing.
+func (d *Deployment) waitForChecks(ctx context.Context, jobID string, batch []string) error {
+	ticker := time.NewTicker(time.Second * 1)
+	defer ticker.Stop()
+	timeout := time.NewTimer(d.timeout)
+	defer timeout.Stop()
+
+	for {
+		unhealthy := ""
+		for _, allocationID := range batch {
+			failing, err := d.unhealthyChecks(ctx, allocationID)
+			if err != nil {
+				return err
+			}
+			if len(failing) > 0 {
+				log.Info(ctx, "allocation checks failing - will re-test", log.Data{"job": jobID, "allocation": allocationID, "checks": failing})
+				unhealthy = allocationID
+				break
+			}
+		}
+		if len(unhealthy) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Warn(ctx, "bailing on allocation checks", log.Data{"job": jobID, "allocation": unhealthy})
+			return &RestartError{AllocationID: unhealthy, JobID: jobID}
+		case <-timeout.C:
+			return &TimeoutError{Action: "restart"}
+		case <-ticker.C:
+		}
+	}
+}
+
+// unhealthyChecks returns the names of the allocation's failing Nomad service
+// checks, and of its Consul services unless its deployment health is healthy.
+// Nomad only reports the result of Consul checks through deployment health, so
+// the Consul services of an allocation that is not part of a deployment are not
+// checked.
+func (d *Deployment) unhealthyChecks(ctx context.Context, allocationID string) ([]string, error) {
+	failing, err := d.failingChecks(ctx, allocationID)
+	if err != nil {
+		return nil, err
+	}
+	allocation, err := d.nomadClient.GetAllocation(ctx, allocationID)
+	if err != nil {
+		return nil, err
+	}
+	status := allocation.DeploymentStatus
+	if status != nil && (status.Healthy == nil || !*status.Healthy) {
+		failing = append(failing, consulServices(allocation)...)
+	}
+	return failing, nil
+}
+
+// consulServices returns the names of the services of the allocation's task
+// group that are registered with Consul.
+func consulServices(allocation *api.Allocation) []string {
+	if allocation.Job == nil {
+		return nil
+	}
+	var names []string
+	for _, tg := range allocation.Job.TaskGroups {
+		if tg.Name == nil || *tg.Name != allocation.TaskGroup {
+			continue
+		}
+		services := tg.Services
+		for _, task := range tg.Tasks {
+			services = append(services, task.Services...)
+		}
+		for _, service := range services {
+			if service.Provider == "" || service.Provider == api.ServiceProviderConsul {
+				names = append(names, service.Name)
+			}
+		}
+	}
+	return names
+}
+
+// pause waits between batches of restarts.
+func (d *Deployment) pause(ctx context.Context, jobID string) error {
+	if d.restartBatchPause <= 0 {
+		return nil
+	}
+	log.Info(ctx, "pausing before next allocation batch", log.Data{"job": jobID, "pause": d.restartBatchPause.String()})
+
+	timer := time.NewTimer(d.restartBatchPause)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return &RestartError{JobID: jobID}
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,103 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-deployer/nomadclient"
+	"github.com/hashicorp/nomad/api"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRollingRestart(t *testing.T) {
+	Convey("running allocations are restarted in batches", t, func() {
+		var mu sync.Mutex
+		group := "web"
+		restarts := map[string]uint64{}
+		checked := map[string]int{}
+		client := &nomadclient.ClientMock{
+			ListAllocationsFunc: func(ctx context.Context, jobID string) ([]api.AllocationListStub, error) {
+				return []api.AllocationListStub{
+					{ID: "a1", ClientStatus: "running", DesiredStatus: "run"},
+					{ID: "a2", ClientStatus: "running", DesiredStatus: "run"},
+					{ID: "a3", ClientStatus: "complete", DesiredStatus: "stop"},
+					{ID: "a4", ClientStatus: "running", DesiredStatus: "run"},
+				}, nil
+			},
+			GetAllocationFunc: func(ctx context.Context, allocationID string) (*api.Allocation, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				return &api.Allocation{ID: allocationID, ClientStatus: "running", TaskStates: map[string]*api.TaskState{"web": {State: "running", Restarts: restarts[allocationID]}}}, nil
+			},
+			RestartAllocationFunc: func(ctx context.Context, allocationID string) error {
+				mu.Lock()
+				defer mu.Unlock()
+				restarts[allocationID]++
+				return nil
+			},
+			GetAllocationChecksFunc: func(ctx context.Context, allocationID string) (api.AllocCheckStatuses, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				checked[allocationID]++
+				if allocationID == "a4" && checked[allocationID] == 1 {
+					return api.AllocCheckStatuses{"c1": {Check: "healthcheck", Status: "failure"}}, nil
+				}
+				return api.AllocCheckStatuses{"c1": {Check: "healthcheck", Status: "success"}}, nil
+			},
+		}
+		dep := &Deployment{timeout: normalTimeout, nomadClient: client, restartBatchSize: 2, restartBatchPause: time.Millisecond * 100}
+
+		res, err := dep.rollingRestart(context.Background(), "test")
+		So(err, ShouldBeNil)
+		So(res, ShouldResemble, &RestartResult{Job: "test", Batches: [][]string{{"a1", "a2"}, {"a4"}}})
+		So(restarts, ShouldResemble, map[string]uint64{"a1": 1, "a2": 1, "a4": 1})
+		So(checked["a4"], ShouldEqual, 2)
+
+		Convey("and stop at a batch that fails to restart", func() {
+			client.GetAllocationFunc = func(ctx context.Context, allocationID string) (*api.Allocation, error) {
+				return &api.Allocation{ID: allocationID, ClientStatus: "failed"}, nil
+			}
+			res, err := dep.rollingRestart(context.Background(), "test")
+			So(err, ShouldHaveSameTypeAs, &RestartError{})
+			So(res.Batches, ShouldResemble, [][]string{{"a1", "a2"}})
+		})
+
+		Convey("and stop when the checks can't be read", func() {
+			checksErr := errors.New("permission denied")
+			client.GetAllocationChecksFunc = func(ctx context.Context, allocationID string) (api.AllocCheckStatuses, error) {
+				return nil, checksErr
+			}
+			res, err := dep.rollingRestart(context.Background(), "test")
+			So(err, ShouldEqual, checksErr)
+			So(res.Batches, ShouldResemble, [][]string{{"a1", "a2"}})
+		})
+
+		Convey("and wait for the deployment health of consul services", func() {
+			healthy := false
+			job := &api.Job{TaskGroups: []*api.TaskGroup{{Name: &group, Tasks: []*api.Task{{Name: "web", Services: []*api.Service{{Name: "web"}, {Name: "metrics", Provider: "nomad"}}}}}}}
+			client.GetAllocationFunc = func(ctx context.Context, allocationID string) (*api.Allocation, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				return &api.Allocation{
+					ID:               allocationID,
+					ClientStatus:     "running",
+					TaskGroup:        group,
+					Job:              job,
+					DeploymentStatus: &api.AllocDeploymentStatus{Healthy: &healthy},
+					TaskStates:       map[string]*api.TaskState{"web": {State: "running", Restarts: restarts[allocationID]}},
+				}, nil
+			}
+			dep.timeout = shortTimeout
+			res, err := dep.rollingRestart(context.Background(), "test")
+			So(err, ShouldResemble, &TimeoutError{Action: "restart"})
+			So(res.Batches, ShouldResemble, [][]string{{"a1", "a2"}})
+
+			healthy = true
+			_, err = dep.rollingRestart(context.Background(), "test")
+			So(err, ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,76 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/message"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// ScaleResult represents the result of scaling a task group.
+type ScaleResult struct {
+	Job      string
+	Group    string
+	Previous int
+	Count    int
+}
+
+// ScaleHandler handles scale messages that are delegated by the engine. The
+// count of the task group in the service's job is changed and monitored until
+// the job is healthy.
+// TODO This function will be removed once the new queue has been implemented
+func (d *Deployment) ScaleHandler(ctx context.Context, msg *engine.Message) error {
+	return d.handle(ctx, "ScaleHandler", engineRequest(msg, msg.Service), func(ctx context.Context, d *Deployment) (interface{}, error) {
+		return result(d.scale(ctx, msg.ID, msg.Service, msg.Group, msg.Count))
+	})
+}
+
+func (d *Deployment) scaleNew(ctx context.Context, msg *message.MessageSQS) error {
+	return d.handle(ctx, "scaleNew", sqsRequest(msg, msg.Job), func(ctx context.Context, d *Deployment) (interface{}, error) {
+		return result(d.scale(ctx, msg.ID, msg.Job, msg.Group, msg.Count))
+	})
+}
+
+// scale sets the count of the job's task group, within the configured bounds,
+// and waits for the job to be healthy. A failed change is rolled back like a
+// failed deployment.
+func (d *Deployment) scale(ctx context.Context, correlationID, jobID, group string, count *int) (*ScaleResult, error) {
+	if count == nil {
+		return nil, &ScaleError{Group: group, Reason: "missing count"}
+	}
+	if *count < d.scaleMin || *count > d.scaleMax {
+		return nil, &ScaleError{Count: count, Group: group, Reason: fmt.Sprintf("count must be between %d and %d", d.scaleMin, d.scaleMax)}
+	}
+
+	jobInfo, err := d.nomadClient.GetJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	result := &ScaleResult{Job: jobID, Group: group, Count: *count}
+	found := false
+	for _, tg := range jobInfo.TaskGroups {
+		if tg.Name != nil && *tg.Name == group {
+			found = true
+			if tg.Count != nil {
+				result.Previous = *tg.Count
+			}
+		}
+	}
+	if !found {
+		return nil, &ScaleError{Count: count, Group: group, Reason: "unknown task group"}
+	}
+
+	logData := log.Data{"job": jobID, "group": group, "previous": result.Previous, "count": *count}
+	log.Info(ctx, "scaling task group", logData)
+	res, err := d.nomadClient.ScaleJob(ctx, jobID, group, *count, fmt.Sprintf("scaled by dp-deployer for message %s", correlationID))
+	if err != nil {
+		return nil, err
+	}
+	if err := d.deploymentSuccessCheck(ctx, correlationID, res.EvalID, jobID, res.JobModifyIndex, false); err != nil {
+		return result, d.rollback(ctx, correlationID, jobID, err)
+	}
+	log.Info(ctx, "task group scaled", logData)
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	scalableJobInfo = `{"ID": "test", "Name": "test", "Type": "service", "Version": 2, "TaskGroups": [{"Name": "web", "Count": 2}]}`
+	scaleSuccess    = `{"EvalID": "12345", "JobModifyIndex": 99}`
+)
+
+func TestScale(t *testing.T) {
+	withMocks(func() {
+		Convey("task groups are scaled", t, func() {
+			ctx := context.Background()
+			var scaled *api.ScalingRequest
+			httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test"), httpmock.NewStringResponder(200, scalableJobInfo))
+			httpmock.RegisterResponder("POST", fmt.Sprintf(scaleURL, nomadURL, "test"), func(req *http.Request) (*http.Response, error) {
+				b, _ := io.ReadAll(req.Body)
+				json.Unmarshal(b, &scaled)
+				return httpmock.NewStringResponse(200, scaleSuccess), nil
+			})
+			httpmock.RegisterResponder("GET", fmt.Sprintf(deploymentURL, nomadURL, "test"), httpmock.NewStringResponder(200, deploymentSuccess))
+			dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient, scaleMin: 1, scaleMax: 5}
+			count := func(n int) *int { return &n }
+
+			Convey("and monitored until healthy", func() {
+				res, err := dep.scale(ctx, "1", "test", "web", count(4))
+				So(err, ShouldBeNil)
+				So(res, ShouldResemble, &ScaleResult{Job: "test", Group: "web", Previous: 2, Count: 4})
+				So(*scaled.Count, ShouldEqual, 4)
+				So(scaled.Target, ShouldResemble, map[string]string{"Job": "test", "Group": "web"})
+			})
+
+			Convey("within the configured bounds", func() {
+				_, err := dep.scale(ctx, "1", "test", "web", count(6))
+				So(err, ShouldResemble, &ScaleError{Count: count(6), Group: "web", Reason: "count must be between 1 and 5"})
+				_, err = dep.scale(ctx, "1", "test", "web", count(0))
+				So(err, ShouldHaveSameTypeAs, &ScaleError{})
+				_, err = dep.scale(ctx, "1", "test", "web", nil)
+				So(err, ShouldResemble, &ScaleError{Group: "web", Reason: "missing count"})
+				So(scaled, ShouldBeNil)
+			})
+
+			Convey("that exist", func() {
+				_, err := dep.scale(ctx, "1", "test", "api", count(3))
+				So(err, ShouldResemble, &ScaleError{Count: count(3), Group: "api", Reason: "unknown task group"})
+				So(scaled, ShouldBeNil)
+			})
+		})
+	})
+}
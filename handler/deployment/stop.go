@@ -0,0 +1,138 @@
+package deployment
+
+import (
+	"context"
+	"time"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/message"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/hashicorp/nomad/api"
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// StopResult represents the result of stopping a job.
+type StopResult struct {
+	Job         string
+	Purged      bool
+	Plan        *PlanResult
+	Allocations []StoppedAllocation
+}
+
+// StoppedAllocation represents an allocation of a stopped job.
+type StoppedAllocation struct {
+	ID           string
+	Name         string
+	NodeID       string
+	ClientStatus string
+}
+
+// StopHandler handles stop messages that are delegated by the engine. The
+// service's job is stopped, and purged if asked, once its allocations have all
+// stopped.
+// TODO This function will be removed once the new queue has been implemented
+func (d *Deployment) StopHandler(ctx context.Context, msg *engine.Message) error {
+	return d.handle(ctx, "StopHandler", engineRequest(msg), func(ctx context.Context, d *Deployment) (interface{}, error) {
+		return result(d.stop(ctx, msg.ID, msg.Service, msg.Purge))
+	})
+}
+
+func (d *Deployment) stopNew(ctx context.Context, msg *message.MessageSQS) error {
+	return d.handle(ctx, "stopNew", sqsRequest(msg), func(ctx context.Context, d *Deployment) (interface{}, error) {
+		return result(d.stop(ctx, msg.ID, msg.Job, msg.Purge))
+	})
+}
+
+// stop plans the deregistration of the job, then stops it and waits for all its
+// allocations to reach a terminal state. Protected jobs are never stopped.
+func (d *Deployment) stop(ctx context.Context, correlationID, jobID string, purge bool) (*StopResult, error) {
+	for _, protected := range d.protectedJobs {
+		if jobID == protected {
+			return nil, &ProtectedJobError{Job: jobID}
+		}
+	}
+
+	jobInfo, err := d.nomadClient.GetJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	result := &StopResult{Job: jobID, Purged: purge}
+	if result.Plan, err = d.planStop(ctx, jobID, jobInfo); err != nil {
+		return nil, err
+	}
+	log.Info(ctx, "stopping job", log.Data{"job": jobID, "purge": purge, "plan": result.Plan.Rendered})
+
+	res, err := d.nomadClient.DeregisterJob(ctx, jobID, purge)
+	if err != nil {
+		return result, err
+	}
+	allocations, err := d.waitForStopped(ctx, correlationID, res.EvalID, jobID)
+	for _, allocation := range allocations {
+		result.Allocations = append(result.Allocations, StoppedAllocation{
+			ID:           allocation.ID,
+			Name:         allocation.Name,
+			NodeID:       allocation.NodeID,
+			ClientStatus: allocation.ClientStatus,
+		})
+	}
+	return result, err
+}
+
+// planStop plans the job as stopped, which is how Nomad plans its
+// deregistration. The plan policy does not apply, as every allocation stops.
+func (d *Deployment) planStop(ctx context.Context, jobID string, jobInfo *api.Job) (*PlanResult, error) {
+	stopped := *jobInfo
+	stop := true
+	stopped.Stop = &stop
+
+	res, err := d.nomadClient.PlanJob(ctx, jobID, &stopped, true)
+	if err != nil {
+		return nil, err
+	}
+	result := &PlanResult{Diff: res.Diff, Rendered: renderDiff(res.Diff), Warnings: res.Warnings}
+	if res.Annotations != nil {
+		result.Updates = res.Annotations.DesiredTGUpdates
+	}
+	return result, nil
+}
+
+// waitForStopped waits until all the job's allocations have reached a terminal
+// state and returns them.
+func (d *Deployment) waitForStopped(ctx context.Context, correlationID, evaluationID, jobID string) ([]api.AllocationListStub, error) {
+	checks, stop := d.watch(jobID)
+	defer stop()
+	timeout := time.NewTimer(d.timeout)
+	defer timeout.Stop()
+	minLogData := log.Data{"evaluation": evaluationID, "job": jobID}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Warn(ctx, "bailing on job stop status", minLogData)
+			return nil, &AbortedError{EvaluationID: evaluationID, CorrelationID: correlationID}
+		case <-timeout.C:
+			return nil, &TimeoutError{Action: "stop"}
+		case <-checks:
+			allocations, err := d.nomadClient.ListAllocations(ctx, jobID)
+			if err != nil {
+				return nil, err
+			}
+			if allTerminal(allocations) {
+				log.Info(ctx, "job stopped", minLogData)
+				return allocations, nil
+			}
+			log.Info(ctx, "job stop incomplete - will re-test", minLogData)
+		}
+	}
+}
+
+func allTerminal(allocations []api.AllocationListStub) bool {
+	for _, allocation := range allocations {
+		switch allocation.ClientStatus {
+		case structs.AllocClientStatusComplete, structs.AllocClientStatusFailed, structs.AllocClientStatusLost:
+		default:
+			return false
+		}
+	}
+	return true
+}
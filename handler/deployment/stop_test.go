@@ -0,0 +1,74 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	allocationsStopping = `[{"ID": "54321", "Name": "test.web[0]", "NodeID": "n1", "ClientStatus": "running", "DesiredStatus": "stop"}]`
+	allocationsStopped  = `[{"ID": "54321", "Name": "test.web[0]", "NodeID": "n1", "ClientStatus": "complete", "DesiredStatus": "stop"}]`
+)
+
+func TestStop(t *testing.T) {
+	withMocks(func() {
+		Convey("jobs are stopped", t, func() {
+			ctx := context.Background()
+			var planned api.JobPlanRequest
+			httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test"), httpmock.NewStringResponder(200, serviceJobInfoSuccess))
+			httpmock.RegisterResponder("POST", fmt.Sprintf(planURL, nomadURL, "test"), func(req *http.Request) (*http.Response, error) {
+				b, _ := io.ReadAll(req.Body)
+				json.Unmarshal(b, &planned)
+				return httpmock.NewStringResponse(200, `{"Annotations": {"DesiredTGUpdates": {"web": {"Stop": 1}}}}`), nil
+			})
+			stopped := 0
+			deregister := func(req *http.Request) (*http.Response, error) {
+				stopped++
+				return httpmock.NewStringResponse(200, `{"EvalID": "12345"}`), nil
+			}
+			httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, "test"), func(req *http.Request) (*http.Response, error) {
+				if stopped > 0 && httpmock.GetCallCountInfo()["GET "+fmt.Sprintf(allocationsURL, nomadURL, "test")] > 1 {
+					return httpmock.NewStringResponse(200, allocationsStopped), nil
+				}
+				return httpmock.NewStringResponse(200, allocationsStopping), nil
+			})
+			dep := &Deployment{timeout: normalTimeout, nomadClient: nomadClient, protectedJobs: []string{"dp-deployer"}}
+
+			Convey("once their allocations have stopped", func() {
+				httpmock.RegisterResponder("DELETE", fmt.Sprintf(infoURL, nomadURL, "test"), deregister)
+				res, err := dep.stop(ctx, "1", "test", false)
+				So(err, ShouldBeNil)
+				So(*planned.Job.Stop, ShouldBeTrue)
+				So(planned.Diff, ShouldBeTrue)
+				So(res.Plan.Updates["web"].Stop, ShouldEqual, 1)
+				So(res.Plan.Violations, ShouldBeEmpty)
+				So(res.Allocations, ShouldResemble, []StoppedAllocation{{ID: "54321", Name: "test.web[0]", NodeID: "n1", ClientStatus: "complete"}})
+				So(res.Purged, ShouldBeFalse)
+				So(stopped, ShouldEqual, 1)
+			})
+
+			Convey("and purged if asked", func() {
+				httpmock.RegisterResponder("DELETE", fmt.Sprintf(infoURL, nomadURL, "test")+"?purge=true", deregister)
+				res, err := dep.stop(ctx, "1", "test", true)
+				So(err, ShouldBeNil)
+				So(res.Purged, ShouldBeTrue)
+				So(stopped, ShouldEqual, 1)
+			})
+
+			Convey("unless they are protected", func() {
+				res, err := dep.stop(ctx, "1", "dp-deployer", false)
+				So(res, ShouldBeNil)
+				So(err, ShouldResemble, &ProtectedJobError{Job: "dp-deployer"})
+				So(stopped, ShouldEqual, 0)
+			})
+		})
+	})
+}
@@ -0,0 +1,60 @@
+package deployment
+
+import "encoding/json"
+
+// target returns a copy of the deployment that makes its requests to the
+// namespace and region, where they are set, instead of the configured ones.
+func (d *Deployment) target(namespace, region string) *Deployment {
+	t := *d
+	if len(namespace) > 0 {
+		t.namespace = namespace
+	}
+	if len(region) > 0 {
+		t.region = region
+	}
+	t.nomadClient = d.nomadClient.Target(namespace, region)
+	return &t
+}
+
+// authorise returns a copy of the deployment that authorises its requests with
+// Nomad tokens from Vault, if the cluster has them, for the role of the message's
+// signer, or else the role of the targeted namespace, or else the default role.
+func (d *Deployment) authorise(signer string) *Deployment {
+	if d.tokens == nil {
+		return d
+	}
+	role := d.tokenRole
+	if r, ok := d.namespaceRoles[d.namespace]; ok {
+		role = r
+	}
+	if r, ok := d.signerRoles[signer]; ok {
+		role = r
+	}
+	t := *d
+	t.nomadClient = d.nomadClient.WithToken(d.tokens.Role(role))
+	return &t
+}
+
+// targetJob sets the namespace and region of the job payload to the targeted
+// ones, and its datacenters to the given ones if there are any, so the job is
+// registered where it is planned and monitored.
+func (d *Deployment) targetJob(j []byte, datacenters []string) ([]byte, error) {
+	if len(d.namespace) == 0 && len(d.region) == 0 && len(datacenters) == 0 {
+		return j, nil
+	}
+
+	var p payload
+	if err := json.Unmarshal(j, &p); err != nil || p.Job == nil {
+		return j, err
+	}
+	if len(d.namespace) > 0 {
+		p.Job.Namespace = &d.namespace
+	}
+	if len(d.region) > 0 {
+		p.Job.Region = &d.region
+	}
+	if len(datacenters) > 0 {
+		p.Job.Datacenters = datacenters
+	}
+	return json.Marshal(p)
+}
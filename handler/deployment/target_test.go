@@ -0,0 +1,124 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/nomadclient"
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTarget(t *testing.T) {
+	Convey("messages override the configured namespace and region", t, func() {
+		client := nomadclient.New(nomadURL, "", nil, 0, 0).Target("dp", "eu")
+		dep := &Deployment{nomadClient: client, namespace: "dp", region: "eu"}
+		So(dep.target("", ""), ShouldResemble, dep)
+
+		targeted := dep.target("other", "us")
+		So(targeted.namespace, ShouldEqual, "other")
+		So(targeted.region, ShouldEqual, "us")
+		So(targeted.nomadClient, ShouldResemble, client.Target("other", "us"))
+		So(dep.namespace, ShouldEqual, "dp")
+		So(dep.nomadClient, ShouldEqual, client)
+	})
+
+	Convey("jobs are registered in the target namespace, region and datacenters", t, func() {
+		j := []byte(`{"Job": {"ID": "test", "Region": "global", "Datacenters": ["dc1"]}}`)
+
+		b, err := (&Deployment{}).targetJob(j, nil)
+		So(err, ShouldBeNil)
+		So(b, ShouldResemble, j)
+
+		b, err = (&Deployment{namespace: "dp", region: "eu"}).targetJob(j, []string{"eu-west-2"})
+		So(err, ShouldBeNil)
+		var p payload
+		So(json.Unmarshal(b, &p), ShouldBeNil)
+		So(*p.Job.ID, ShouldEqual, "test")
+		So(*p.Job.Namespace, ShouldEqual, "dp")
+		So(*p.Job.Region, ShouldEqual, "eu")
+		So(p.Job.Datacenters, ShouldResemble, []string{"eu-west-2"})
+	})
+}
+
+func TestTargetedRun(t *testing.T) {
+	withMocks(func() {
+		Convey("targeted jobs are registered and monitored in their namespace and region", t, func() {
+			query := "?namespace=dp&region=us"
+			var registered payload
+			httpmock.RegisterResponder("POST", fmt.Sprintf(runURL, nomadURL)+query, func(req *http.Request) (*http.Response, error) {
+				b, _ := io.ReadAll(req.Body)
+				json.Unmarshal(b, &registered)
+				return httpmock.NewStringResponse(200, jobSuccess), nil
+			})
+			httpmock.RegisterResponder("GET", fmt.Sprintf(infoURL, nomadURL, "test")+query, httpmock.NewStringResponder(200, systemJobInfoSuccess))
+			httpmock.RegisterResponder("GET", fmt.Sprintf(allocationsURL, nomadURL, "test")+query, httpmock.NewStringResponder(200, allocationsSuccess))
+
+			dep := (&Deployment{timeout: normalTimeout, nomadClient: nomadClient.Target("dp", "eu"), namespace: "dp", region: "eu"}).target("", "us")
+			err := dep.run(context.Background(), &engine.Message{ID: "1", Service: "test", Datacenters: []string{"us-east-1"}})
+			So(err, ShouldBeNil)
+			So(registered.Job, ShouldNotBeNil)
+			So(*registered.Job.Namespace, ShouldEqual, "dp")
+			So(*registered.Job.Region, ShouldEqual, "us")
+			So(registered.Job.Datacenters, ShouldResemble, []string{"us-east-1"})
+			So(httpmock.GetCallCountInfo()["GET "+fmt.Sprintf(allocationsURL, nomadURL, "test")+query], ShouldBeGreaterThan, 0)
+		})
+	})
+}
+
+func TestAuthorise(t *testing.T) {
+	Convey("requests are authorised with the token of the signer's, namespace's or default role", t, func() {
+		vc := &vaultClientMock{}
+		tokens := nomadclient.NewVaultTokens(vc, "nomad")
+		var roles []string
+		var client *nomadclient.ClientMock
+		client = &nomadclient.ClientMock{
+			TargetFunc: func(namespace, region string) nomadclient.Client {
+				return client
+			},
+			WithTokenFunc: func(token nomadclient.TokenSource) nomadclient.Client {
+				token.Token(context.Background())
+				roles = append(roles, vc.role)
+				return nil
+			},
+		}
+
+		dep := &Deployment{nomadClient: client}
+		So(dep.authorise("signer"), ShouldEqual, dep)
+
+		dep = &Deployment{
+			nomadClient:    client,
+			namespace:      "dp",
+			tokens:         tokens,
+			tokenRole:      "deployer",
+			namespaceRoles: map[string]string{"dp": "dp-deployer"},
+			signerRoles:    map[string]string{"signer": "admin"},
+		}
+		dep.authorise("signer")
+		dep.authorise("other")
+		dep.target("other", "").authorise("other")
+		So(roles, ShouldResemble, []string{"admin", "dp-deployer", "deployer"})
+		So(dep.nomadClient, ShouldEqual, client)
+	})
+}
+
+// vaultClientMock records the role of the last Nomad token read.
+type vaultClientMock struct {
+	role string
+}
+
+func (v *vaultClientMock) Read(path string) (*vaultapi.Secret, error) {
+	v.role = strings.TrimPrefix(path, "nomad/creds/")
+	return &vaultapi.Secret{LeaseDuration: 3600, Data: map[string]interface{}{"secret_id": v.role}}, nil
+}
+
+func (v *vaultClientMock) Write(path string, data map[string]interface{}) (*vaultapi.Secret, error) {
+	return nil, nil
+}
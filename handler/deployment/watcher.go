@@ -0,0 +1,354 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/dp-deployer/nomadclient"
+	dphttp "github.com/ONSdigital/dp-net/v2/http"
+	nomad "github.com/ONSdigital/dp-nomad"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	eventStreamURL   = "%s/v1/event/stream?topic=Deployment&topic=Allocation&topic=Evaluation&index=%d"
+	allocationsURL   = "%s/v1/job/%s/allocations"
+	deploymentURL    = "%s/v1/job/%s/deployments"
+	blockingQueryURL = "%s?index=%d&wait=%s"
+	blockingWait     = "30s"
+
+	// allNamespaces streams events from every namespace.
+	allNamespaces    = "*"
+	defaultNamespace = "default"
+)
+
+var (
+	// resyncInterval is how often jobs are checked without any events, so a
+	// missed event only delays a check.
+	resyncInterval = time.Second * 10
+	// retryInterval is the time between reconnecting to the event stream and
+	// between failed blocking queries.
+	retryInterval = time.Second * 5
+)
+
+// Watcher watches the Nomad event stream of the configured region and signals
+// the monitors of the jobs that events are for. While the stream is broken, and
+// for jobs in other regions, monitored jobs are watched with blocking queries
+// instead.
+type Watcher struct {
+	client   dphttp.Clienter
+	endpoint string
+	token    nomadclient.TokenSource
+	region   string
+	retry    time.Duration
+
+	mu        sync.Mutex
+	ctx       context.Context
+	monitors  map[*monitor]struct{}
+	streaming bool
+	index     uint64
+}
+
+// monitor represents a job being monitored.
+type monitor struct {
+	namespace string
+	region    string
+	jobID     string
+	c         chan struct{}
+	fallback  context.CancelFunc
+}
+
+// NewWatcher returns a new watcher of the event stream of the cluster at the
+// endpoint.
+func NewWatcher(nomadClient *nomad.Client, endpoint string, token nomadclient.TokenSource, region string) *Watcher {
+	return &Watcher{
+		client:   nomadClient.Client,
+		endpoint: endpoint,
+		token:    token,
+		region:   region,
+		retry:    retryInterval,
+		ctx:      context.Background(),
+		monitors: make(map[*monitor]struct{}),
+	}
+}
+
+// Start watches the event stream until the context is done, reconnecting when
+// the stream breaks.
+func (w *Watcher) Start(ctx context.Context) {
+	w.mu.Lock()
+	w.ctx = ctx
+	w.mu.Unlock()
+
+	for {
+		err := w.stream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn(ctx, "nomad event stream broken - falling back to blocking queries", log.Data{"error": err})
+		w.setStreaming(false)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.retry):
+		}
+	}
+}
+
+// Subscribe returns a channel that signals when the job in the namespace and
+// region should be checked, starting with an immediate signal, and a function
+// that ends the subscription. Empty namespaces and regions are the defaults.
+func (w *Watcher) Subscribe(namespace, region, jobID string) (<-chan struct{}, func()) {
+	if len(namespace) == 0 {
+		namespace = defaultNamespace
+	}
+	m := &monitor{namespace: namespace, region: region, jobID: jobID, c: make(chan struct{}, 1)}
+	m.c <- struct{}{}
+
+	w.mu.Lock()
+	w.monitors[m] = struct{}{}
+	if !w.streaming || !w.streamed(m) {
+		w.startFallback(m)
+	}
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(resyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m.signal()
+			}
+		}
+	}()
+
+	return m.c, func() {
+		close(done)
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.monitors, m)
+		if m.fallback != nil {
+			m.fallback()
+		}
+	}
+}
+
+// stream reads the event stream, signalling the monitors of the jobs that
+// events are for, until it breaks.
+func (w *Watcher) stream(ctx context.Context) error {
+	w.mu.Lock()
+	url := fmt.Sprintf(eventStreamURL, w.endpoint, w.index)
+	w.mu.Unlock()
+
+	res, err := w.do(ctx, url, allNamespaces, w.region)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	log.Info(ctx, "watching nomad event stream")
+	w.setStreaming(true)
+
+	dec := json.NewDecoder(res.Body)
+	for {
+		// api.Events can't be decoded as its Err field is an interface.
+		var events struct {
+			Index  uint64
+			Events []api.Event
+		}
+		if err := dec.Decode(&events); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		// Heartbeats are empty objects.
+		if events.Index == 0 {
+			continue
+		}
+
+		w.mu.Lock()
+		w.index = events.Index
+		w.mu.Unlock()
+		for _, event := range events.Events {
+			if namespace, jobID := eventJob(&event); len(jobID) > 0 {
+				w.notify(namespace, jobID)
+			}
+		}
+	}
+}
+
+// eventJob returns the namespace and ID of the job that an event is for.
+func eventJob(event *api.Event) (string, string) {
+	switch event.Topic {
+	case api.TopicDeployment:
+		if d, err := event.Deployment(); err == nil && d != nil {
+			return d.Namespace, d.JobID
+		}
+	case api.TopicAllocation:
+		if a, err := event.Allocation(); err == nil && a != nil {
+			return a.Namespace, a.JobID
+		}
+	case api.TopicEvaluation:
+		if e, err := event.Evaluation(); err == nil && e != nil {
+			return e.Namespace, e.JobID
+		}
+	}
+	return "", ""
+}
+
+func (w *Watcher) notify(namespace, jobID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for m := range w.monitors {
+		if m.jobID == jobID && m.namespace == namespace && w.streamed(m) {
+			m.signal()
+		}
+	}
+}
+
+// streamed reports whether the monitored job's events are in the event stream.
+func (w *Watcher) streamed(m *monitor) bool {
+	return len(m.region) == 0 || m.region == w.region
+}
+
+// setStreaming records whether the event stream is connected, starting or
+// stopping the blocking queries of the monitored jobs.
+func (w *Watcher) setStreaming(streaming bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming == streaming {
+		return
+	}
+	w.streaming = streaming
+
+	for m := range w.monitors {
+		if !w.streamed(m) {
+			continue
+		}
+		if !streaming {
+			w.startFallback(m)
+			continue
+		}
+		if m.fallback != nil {
+			m.fallback()
+			m.fallback = nil
+		}
+		// Events may have been missed while reconnecting.
+		m.signal()
+	}
+}
+
+// startFallback watches the monitored job's allocations and deployments with
+// blocking queries. The caller must hold the lock.
+func (w *Watcher) startFallback(m *monitor) {
+	if m.fallback != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(w.ctx)
+	m.fallback = cancel
+	go w.blockingQuery(ctx, m, fmt.Sprintf(allocationsURL, w.endpoint, m.jobID))
+	go w.blockingQuery(ctx, m, fmt.Sprintf(deploymentURL, w.endpoint, m.jobID))
+}
+
+// blockingQuery signals the monitor each time the query's index changes.
+func (w *Watcher) blockingQuery(ctx context.Context, m *monitor, url string) {
+	var index uint64
+	for ctx.Err() == nil {
+		res, err := w.do(ctx, fmt.Sprintf(blockingQueryURL, url, index, blockingWait), m.namespace, m.region)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn(ctx, "blocking query failed - will retry", log.Data{"error": err, "url": url})
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(w.retry):
+			}
+			continue
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+
+		newIndex, err := strconv.ParseUint(res.Header.Get("X-Nomad-Index"), 10, 64)
+		if err != nil {
+			continue
+		}
+		if index > 0 && newIndex != index {
+			m.signal()
+		}
+		index = newIndex
+	}
+}
+
+// do makes a request to the namespace and region without the client's timeout,
+// as streams and blocking queries are long-lived.
+func (w *Watcher) do(ctx context.Context, url, namespace, region string) (*http.Response, error) {
+	token, err := w.token.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	nomadclient.SetTarget(req.URL, namespace, region)
+	req.Header.Set("X-Nomad-Token", token)
+
+	res, err := w.client.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		return nil, &ClientResponseError{Body: string(b), StatusCode: res.StatusCode, URL: req.URL.String()}
+	}
+	return res, nil
+}
+
+// signal signals the monitor unless a signal is already pending.
+func (m *monitor) signal() {
+	select {
+	case m.c <- struct{}{}:
+	default:
+	}
+}
+
+// watch returns a channel that signals when the job should be checked, and a
+// function that stops it. Jobs are checked on their events when there is a
+// watcher, otherwise every second.
+func (d *Deployment) watch(jobID string) (<-chan struct{}, func()) {
+	if d.watcher != nil {
+		return d.watcher.Subscribe(d.namespace, d.region, jobID)
+	}
+
+	c := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second * 1)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				select {
+				case c <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return c, func() { close(done) }
+}
@@ -0,0 +1,152 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ONSdigital/dp-deployer/nomadclient"
+	dphttp "github.com/ONSdigital/dp-net/v2/http"
+	nomad "github.com/ONSdigital/dp-nomad"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const allocationEvent = `{"Index": %d, "Events": [{"Topic": "Allocation", "Type": "AllocationUpdated", "Key": "1", "Index": %d, "Payload": {"Allocation": {"ID": "1", "Namespace": "%s", "JobID": "%s"}}}]}`
+
+// fakeNomad is a Nomad server that streams the events it is sent, or fails
+// the event stream when broken, and answers blocking queries with an index
+// that changes on every query.
+type fakeNomad struct {
+	*httptest.Server
+	events  chan string
+	broken  bool
+	queries int64
+}
+
+func newFakeNomad(broken bool) *fakeNomad {
+	f := &fakeNomad{events: make(chan string), broken: broken}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/event/stream", func(w http.ResponseWriter, req *http.Request) {
+		if f.broken {
+			http.Error(w, "stream unavailable", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintln(w, "{}")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case <-req.Context().Done():
+				return
+			case event := <-f.events:
+				fmt.Fprintln(w, event)
+				w.(http.Flusher).Flush()
+			}
+		}
+	})
+	mux.HandleFunc("/v1/job/", func(w http.ResponseWriter, req *http.Request) {
+		index := atomic.AddInt64(&f.queries, 1)
+		time.Sleep(time.Millisecond * 50)
+		w.Header().Set("X-Nomad-Index", strconv.FormatInt(index, 10))
+		fmt.Fprint(w, "[]")
+	})
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+func (w *Watcher) isStreaming() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.streaming
+}
+
+func signalled(c <-chan struct{}, timeout time.Duration) bool {
+	select {
+	case <-c:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	defaultRetryInterval := retryInterval
+	retryInterval = time.Millisecond * 100
+	defer func() { retryInterval = defaultRetryInterval }()
+
+	newWatcher := func(f *fakeNomad) *Watcher {
+		return NewWatcher(&nomad.Client{Client: dphttp.NewClient(), URL: f.URL}, f.URL, nomadclient.StaticToken(""), "")
+	}
+
+	Convey("monitors are signalled by the event stream", t, func() {
+		f := newFakeNomad(false)
+		defer f.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		w := newWatcher(f)
+		go w.Start(ctx)
+
+		checks, stop := w.Subscribe("", "", "test")
+		defer stop()
+		So(signalled(checks, time.Second), ShouldBeTrue)
+
+		for i := 0; i < 50 && !w.isStreaming(); i++ {
+			time.Sleep(time.Millisecond * 20)
+		}
+		So(w.isStreaming(), ShouldBeTrue)
+		signalled(checks, time.Millisecond*100)
+
+		Convey("events for other jobs are ignored", func() {
+			f.events <- fmt.Sprintf(allocationEvent, 10, 10, "default", "other")
+			f.events <- fmt.Sprintf(allocationEvent, 11, 11, "other", "test")
+			So(signalled(checks, time.Millisecond*200), ShouldBeFalse)
+		})
+
+		Convey("events for the job signal its monitor", func() {
+			f.events <- fmt.Sprintf(allocationEvent, 12, 12, "default", "test")
+			So(signalled(checks, time.Second), ShouldBeTrue)
+		})
+
+		Convey("jobs in other regions are watched with blocking queries", func() {
+			checks, stop := w.Subscribe("", "us", "test")
+			defer stop()
+			So(signalled(checks, time.Second), ShouldBeTrue)
+			So(signalled(checks, time.Second), ShouldBeTrue)
+		})
+	})
+
+	Convey("monitors fall back to blocking queries when the event stream is broken", t, func() {
+		f := newFakeNomad(true)
+		defer f.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		w := newWatcher(f)
+		go w.Start(ctx)
+
+		checks, stop := w.Subscribe("", "", "test")
+		So(signalled(checks, time.Second), ShouldBeTrue)
+		So(w.isStreaming(), ShouldBeFalse)
+		So(signalled(checks, time.Second), ShouldBeTrue)
+
+		Convey("and stop their blocking queries when unsubscribed", func() {
+			stop()
+			time.Sleep(time.Millisecond * 100)
+			queries := atomic.LoadInt64(&f.queries)
+			time.Sleep(time.Millisecond * 200)
+			So(atomic.LoadInt64(&f.queries), ShouldEqual, queries)
+		})
+	})
+}
+
+func TestWatch(t *testing.T) {
+	Convey("jobs without a watcher are checked every second", t, func() {
+		dep := &Deployment{}
+		checks, stop := dep.watch("test")
+		defer stop()
+		So(signalled(checks, time.Millisecond*1500), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,320 @@
+package deployment
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// Bundle error reasons.
+const (
+	reasonFileTooLarge   = "file exceeds the bundle file size limit"
+	reasonBundleTooLarge = "bundle exceeds the bundle size limit"
+	reasonPathTraversal  = "path escapes the workspace"
+	reasonLinkEscape     = "link target escapes the workspace"
+	reasonUnsupported    = "unsupported entry type"
+	reasonFormat         = "unsupported bundle format"
+)
+
+// workspace extracts bundles into a directory, enforcing its size limits and
+// rejecting entries that would be written outside of it. The size extracted is
+// shared with sub-workspaces, so the limit covers every bundle in the message.
+type workspace struct {
+	dir         string
+	maxSize     int64
+	maxFileSize int64
+	size        *int64
+}
+
+// newWorkspace creates a fresh workspace for the message's bundle.
+func (d *Deployment) newWorkspace(msg *engine.Message) (*workspace, error) {
+	if err := os.MkdirAll(d.root, 0700); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(d.root, msg.Service+"-")
+	if err != nil {
+		return nil, err
+	}
+	return &workspace{dir: dir, maxSize: d.bundleMaxSize, maxFileSize: d.bundleMaxFileSize, size: new(int64)}, nil
+}
+
+// sub returns a workspace for a new directory in the workspace.
+func (w *workspace) sub(name string) (*workspace, error) {
+	dir := filepath.Join(w.dir, name)
+	if err := os.Mkdir(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &workspace{dir: dir, maxSize: w.maxSize, maxFileSize: w.maxFileSize, size: w.size}, nil
+}
+
+// cleanupWorkspace removes the message's workspace, unless the deployment
+// failed and failed workspaces are kept for debugging.
+func (d *Deployment) cleanupWorkspace(ctx context.Context, msg *engine.Message, err error) {
+	if len(msg.Workspace) == 0 {
+		return
+	}
+	if err != nil && d.keepFailedWorkspaces {
+		log.Info(ctx, "keeping failed deployment workspace", log.Data{"service": msg.Service, "workspace": msg.Workspace})
+		return
+	}
+	if rerr := os.RemoveAll(msg.Workspace); rerr != nil {
+		log.Error(ctx, "Deployment-cleanupWorkspace, os.RemoveAll() error", rerr)
+	}
+}
+
+// extract extracts a zip or tar.gz bundle into the workspace.
+func (w *workspace) extract(r io.Reader) error {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(zipMagic))
+
+	var err error
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		err = w.extractTarGz(br)
+	case bytes.HasPrefix(magic, zipMagic):
+		if f, ok := r.(*os.File); ok {
+			err = w.extractZipFrom(f)
+		} else {
+			err = w.extractZip(br)
+		}
+	default:
+		err = &BundleError{Reason: reasonFormat}
+	}
+	if err != nil {
+		return err
+	}
+	return w.checkLinks()
+}
+
+func (w *workspace) extractTarGz(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path, err := w.path(hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = w.mkdir(hdr.Name, path)
+		case tar.TypeReg:
+			err = w.writeFile(hdr.Name, path, hdr.FileInfo().Mode(), tr)
+		case tar.TypeSymlink:
+			err = w.symlink(hdr.Name, path, hdr.Linkname)
+		default:
+			err = &BundleError{Entry: hdr.Name, Reason: reasonUnsupported}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip spools the bundle to a file as zip archives must be read from the
+// end.
+func (w *workspace) extractZip(r io.Reader) error {
+	f, err := os.CreateTemp(filepath.Dir(w.dir), filepath.Base(w.dir)+".*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if w.maxSize > 0 {
+		r = io.LimitReader(r, w.maxSize+1)
+	}
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return err
+	}
+	if w.maxSize > 0 && n > w.maxSize {
+		return &BundleError{Reason: reasonBundleTooLarge}
+	}
+	return w.unzip(f, n)
+}
+
+// extractZipFrom extracts a bundle that has already been spooled to a file.
+func (w *workspace) extractZipFrom(f *os.File) error {
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return w.unzip(f, fi.Size())
+}
+
+func (w *workspace) unzip(r io.ReaderAt, n int64) error {
+	zr, err := zip.NewReader(r, n)
+	if err != nil {
+		return err
+	}
+	for _, zf := range zr.File {
+		if err := w.extractZipFile(zf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *workspace) extractZipFile(zf *zip.File) error {
+	path, err := w.path(zf.Name)
+	if err != nil {
+		return err
+	}
+	mode := zf.Mode()
+	if mode.IsDir() {
+		return w.mkdir(zf.Name, path)
+	}
+	if mode&^os.ModeSymlink&os.ModeType != 0 {
+		return &BundleError{Entry: zf.Name, Reason: reasonUnsupported}
+	}
+
+	rc, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if mode&os.ModeSymlink != 0 {
+		target, err := io.ReadAll(io.LimitReader(rc, 4096))
+		if err != nil {
+			return err
+		}
+		return w.symlink(zf.Name, path, string(target))
+	}
+	return w.writeFile(zf.Name, path, mode, rc)
+}
+
+// path returns the workspace path of a bundle entry, rejecting entries that
+// would be written outside of the workspace.
+func (w *workspace) path(name string) (string, error) {
+	if filepath.IsAbs(name) || !filepath.IsLocal(filepath.Clean(name)) {
+		return "", &BundleError{Entry: name, Reason: reasonPathTraversal}
+	}
+	return filepath.Join(w.dir, name), nil
+}
+
+func (w *workspace) mkdir(name, path string) error {
+	if err := w.checkParents(name, path); err != nil {
+		return err
+	}
+	return os.MkdirAll(path, 0700)
+}
+
+// writeFile writes a bundle entry, enforcing the file and total size limits.
+func (w *workspace) writeFile(name, path string, mode os.FileMode, r io.Reader) error {
+	if err := w.checkParents(name, path); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode.Perm()&0755|0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	limit := int64(math.MaxInt64 - 1)
+	if w.maxFileSize > 0 {
+		limit = w.maxFileSize
+	}
+	if w.maxSize > 0 && w.maxSize-*w.size < limit {
+		limit = w.maxSize - *w.size
+	}
+	n, err := io.Copy(f, io.LimitReader(r, limit+1))
+	if err != nil {
+		return err
+	}
+	*w.size += n
+	if w.maxFileSize > 0 && n > w.maxFileSize {
+		return &BundleError{Entry: name, Reason: reasonFileTooLarge}
+	}
+	if w.maxSize > 0 && *w.size > w.maxSize {
+		return &BundleError{Entry: name, Reason: reasonBundleTooLarge}
+	}
+	return nil
+}
+
+// symlink creates a link, rejecting targets outside of the workspace.
+func (w *workspace) symlink(name, path, target string) error {
+	if filepath.IsAbs(target) || !filepath.IsLocal(filepath.Join(filepath.Dir(filepath.Clean(name)), target)) {
+		return &BundleError{Entry: name, Reason: reasonLinkEscape}
+	}
+	if err := w.checkParents(name, path); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.Symlink(target, path)
+}
+
+// checkParents rejects entries written through a link, as the link may
+// resolve outside of the workspace.
+func (w *workspace) checkParents(name, path string) error {
+	for dir := filepath.Dir(path); dir != w.dir && strings.HasPrefix(dir, w.dir); dir = filepath.Dir(dir) {
+		fi, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return &BundleError{Entry: name, Reason: reasonLinkEscape}
+		}
+	}
+	return nil
+}
+
+// checkLinks rejects links that resolve outside of the workspace, which can
+// happen when link targets pass through other links.
+func (w *workspace) checkLinks() error {
+	root, err := filepath.EvalSymlinks(w.dir)
+	if err != nil {
+		return err
+	}
+	return filepath.Walk(w.dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			return err
+		}
+		name, _ := filepath.Rel(w.dir, path)
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return &BundleError{Entry: name, Reason: reasonLinkEscape}
+		}
+		if rel, err := filepath.Rel(root, target); err != nil || !filepath.IsLocal(rel) {
+			return &BundleError{Entry: name, Reason: reasonLinkEscape}
+		}
+		return nil
+	})
+}
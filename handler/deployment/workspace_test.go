@@ -0,0 +1,153 @@
+package deployment
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type bundleEntry struct {
+	name, body, link string
+	mode             os.FileMode
+}
+
+func tarGz(entries ...bundleEntry) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		if len(e.link) > 0 {
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		}
+		if e.mode != 0 {
+			hdr.Mode = int64(e.mode)
+		}
+		tw.WriteHeader(hdr)
+		tw.Write([]byte(e.body))
+	}
+	tw.Close()
+	gz.Close()
+	return b.Bytes()
+}
+
+func zipped(entries ...bundleEntry) []byte {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name}
+		hdr.SetMode(0644)
+		body := e.body
+		if len(e.link) > 0 {
+			hdr.SetMode(os.ModeSymlink | 0777)
+			body = e.link
+		}
+		w, _ := zw.CreateHeader(hdr)
+		w.Write([]byte(body))
+	}
+	zw.Close()
+	return b.Bytes()
+}
+
+func TestWorkspace(t *testing.T) {
+	Convey("bundles are extracted as expected", t, func() {
+		root := t.TempDir()
+		dep := &Deployment{root: root, bundleMaxSize: 1000, bundleMaxFileSize: 400}
+		msg := &engine.Message{Service: "test"}
+		w, err := dep.newWorkspace(msg)
+		So(err, ShouldBeNil)
+		So(filepath.Dir(w.dir), ShouldEqual, root)
+
+		Convey("tar.gz bundles are extracted", func() {
+			err := w.extract(bytes.NewReader(tarGz(
+				bundleEntry{name: "test.nomad", body: "job"},
+				bundleEntry{name: "bin/start-task", body: "#!/bin/sh", mode: 0755},
+				bundleEntry{name: "job.nomad", link: "test.nomad"},
+			)))
+			So(err, ShouldBeNil)
+			b, _ := os.ReadFile(filepath.Join(w.dir, "job.nomad"))
+			So(string(b), ShouldEqual, "job")
+			fi, _ := os.Stat(filepath.Join(w.dir, "bin/start-task"))
+			So(fi.Mode().Perm(), ShouldEqual, 0755)
+		})
+
+		Convey("zip bundles are extracted", func() {
+			err := w.extract(bytes.NewReader(zipped(bundleEntry{name: "dir/test.nomad", body: "job"}, bundleEntry{name: "test.nomad", link: "dir/test.nomad"})))
+			So(err, ShouldBeNil)
+			b, _ := os.ReadFile(filepath.Join(w.dir, "test.nomad"))
+			So(string(b), ShouldEqual, "job")
+		})
+
+		Convey("other formats are rejected", func() {
+			So(w.extract(bytes.NewReader([]byte("job"))), ShouldResemble, &BundleError{Reason: reasonFormat})
+		})
+
+		Convey("path traversal is rejected", func() {
+			So(w.extract(bytes.NewReader(tarGz(bundleEntry{name: "../escape", body: "x"}))), ShouldResemble, &BundleError{Entry: "../escape", Reason: reasonPathTraversal})
+			So(w.extract(bytes.NewReader(zipped(bundleEntry{name: "/escape", body: "x"}))), ShouldResemble, &BundleError{Entry: "/escape", Reason: reasonPathTraversal})
+			_, err := os.Stat(filepath.Join(root, "escape"))
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+
+		Convey("symlink escapes are rejected", func() {
+			So(w.extract(bytes.NewReader(tarGz(bundleEntry{name: "passwd", link: "/etc/passwd"}))), ShouldResemble, &BundleError{Entry: "passwd", Reason: reasonLinkEscape})
+			So(w.extract(bytes.NewReader(zipped(bundleEntry{name: "dir/up", link: "../.."}))), ShouldResemble, &BundleError{Entry: "dir/up", Reason: reasonLinkEscape})
+		})
+
+		Convey("writes through symlinks are rejected", func() {
+			err := w.extract(bytes.NewReader(tarGz(bundleEntry{name: "dir/up", link: ".."}, bundleEntry{name: "dir/up/test.nomad", body: "x"})))
+			So(err, ShouldResemble, &BundleError{Entry: "dir/up/test.nomad", Reason: reasonLinkEscape})
+		})
+
+		Convey("links through other links are rejected", func() {
+			err := w.extract(bytes.NewReader(tarGz(bundleEntry{name: "dir/up", link: ".."}, bundleEntry{name: "secret", link: "dir/up/../../secret"})))
+			So(err, ShouldResemble, &BundleError{Entry: "secret", Reason: reasonLinkEscape})
+		})
+
+		Convey("size limits are enforced", func() {
+			large := string(make([]byte, 401))
+			So(w.extract(bytes.NewReader(tarGz(bundleEntry{name: "large", body: large}))), ShouldResemble, &BundleError{Entry: "large", Reason: reasonFileTooLarge})
+
+			w, _ = dep.newWorkspace(msg)
+			medium := string(make([]byte, 400))
+			err := w.extract(bytes.NewReader(tarGz(bundleEntry{name: "a", body: medium}, bundleEntry{name: "b", body: medium}, bundleEntry{name: "c", body: medium})))
+			So(err, ShouldResemble, &BundleError{Entry: "c", Reason: reasonBundleTooLarge})
+		})
+
+		Convey("the bundle size limit covers every sub-workspace", func() {
+			medium := string(make([]byte, 400))
+			for i, name := range []string{"a", "b"} {
+				sw, err := w.sub(strconv.Itoa(i))
+				So(err, ShouldBeNil)
+				So(sw.extract(bytes.NewReader(tarGz(bundleEntry{name: name, body: medium}))), ShouldBeNil)
+			}
+			sw, err := w.sub("2")
+			So(err, ShouldBeNil)
+			So(sw.extract(bytes.NewReader(tarGz(bundleEntry{name: "c", body: medium}))), ShouldResemble, &BundleError{Entry: "c", Reason: reasonBundleTooLarge})
+		})
+
+		Convey("workspaces are cleaned up", func() {
+			ctx := context.Background()
+			msg.Workspace = w.dir
+
+			dep.keepFailedWorkspaces = true
+			dep.cleanupWorkspace(ctx, msg, errors.New("failed"))
+			_, err := os.Stat(w.dir)
+			So(err, ShouldBeNil)
+
+			dep.cleanupWorkspace(ctx, msg, nil)
+			_, err = os.Stat(w.dir)
+			So(os.IsNotExist(err), ShouldBeTrue)
+		})
+	})
+}
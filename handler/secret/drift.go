@@ -0,0 +1,219 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	"github.com/ONSdigital/log.go/v2/log"
+)
+
+// DriftDetector periodically compares the encrypted secret artifacts with the
+// values held in their secret stores. Values are compared by hash and are never
+// logged. Artifacts that cannot be read are recorded and skipped, so one bad
+// artifact does not hide drift in the others.
+type DriftDetector struct {
+	secret   *Secret
+	lister   ArtifactLister
+	interval time.Duration
+	heal     bool
+
+	mu      sync.RWMutex
+	drifted []string
+	failed  []string
+	checked time.Time
+	err     error
+}
+
+// NewDriftDetector returns a new drift detector. If heal is true, drifted
+// secrets are rewritten from their artifacts. Jobs depending on a healed
+// secret are not restarted.
+func NewDriftDetector(s *Secret, lister ArtifactLister, interval time.Duration, heal bool) *DriftDetector {
+	return &DriftDetector{secret: s, lister: lister, interval: interval, heal: heal}
+}
+
+// Start checks for drift at each interval until the context is done.
+func (d *DriftDetector) Start(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check compares every artifact with its secret store once, healing any drift
+// if configured to. The drifted paths are returned.
+func (d *DriftDetector) Check(ctx context.Context) ([]string, error) {
+	drifted, changes, failed, err := d.detect(ctx)
+	var healed []string
+	if err == nil && d.heal && len(changes) > 0 {
+		log.Info(ctx, "healing secret drift", log.Data{"paths": drifted})
+		if err = d.repair(ctx, changes); err == nil {
+			healed, drifted = drifted, nil
+		}
+	}
+	if err != nil {
+		log.Error(ctx, "DriftDetector-Check, error", err)
+	}
+	log.Info(ctx, "checked secrets for drift", log.Data{"drifted": drifted, "healed": healed, "failed": failed})
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.drifted, d.failed, d.checked, d.err = drifted, failed, time.Now().UTC(), err
+	return drifted, err
+}
+
+// Checker reports drift to the healthcheck.
+func (d *DriftDetector) Checker(ctx context.Context, state *health.CheckState) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	switch {
+	case d.checked.IsZero():
+		return state.Update(health.StatusOK, "secret drift not yet checked", 0)
+	case d.err != nil:
+		return state.Update(health.StatusWarning, "secret drift check failed: "+d.err.Error(), 0)
+	case len(d.drifted) > 0 || len(d.failed) > 0:
+		var msgs []string
+		if len(d.drifted) > 0 {
+			msgs = append(msgs, "secrets have drifted: "+strings.Join(d.drifted, ", "))
+		}
+		if len(d.failed) > 0 {
+			msgs = append(msgs, "artifacts could not be checked: "+strings.Join(d.failed, ", "))
+		}
+		return state.Update(health.StatusWarning, strings.Join(msgs, "; "), 0)
+	}
+	return state.Update(health.StatusOK, "secrets match their artifacts", 0)
+}
+
+// detect returns the drifted paths along with the changes that would heal them,
+// and the artifacts that could not be checked.
+func (d *DriftDetector) detect(ctx context.Context) ([]string, []change, []string, error) {
+	keys, err := d.lister.List("")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var drifted, failed []string
+	var changes []change
+	for _, key := range keys {
+		if strings.Count(key, "/") != 1 || !strings.HasSuffix(key, ".json") {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, nil, &AbortedError{}
+		default:
+		}
+
+		c, match, err := d.compare(ctx, key)
+		if err != nil {
+			log.Error(ctx, "DriftDetector-detect, d.compare() error", err, log.Data{"artifact": key})
+			failed = append(failed, key)
+			continue
+		}
+		if !match {
+			log.Warn(ctx, "secret has drifted from its artifact", log.Data{"artifact": key, "path": c.path, "store": c.store})
+			drifted = append(drifted, c.path)
+			changes = append(changes, c)
+		}
+	}
+	return drifted, changes, failed, nil
+}
+
+// compare reports whether the artifact matches its secret store, along with
+// the change that would make it match.
+func (d *DriftDetector) compare(ctx context.Context, key string) (change, bool, error) {
+	b, _, err := d.secret.s3Client.Get(key)
+	if err != nil {
+		return change{}, false, err
+	}
+	expected, err := d.secret.decryptMessage(b)
+	b.Close()
+	if err != nil {
+		return change{}, false, err
+	}
+
+	path := pathFor(key)
+	name := d.secret.storeName("", path)
+	store, ok := d.secret.stores[name]
+	if !ok {
+		return change{}, false, &UnknownStoreError{Name: name}
+	}
+	current, err := store.Read(ctx, path)
+	if err != nil {
+		return change{}, false, err
+	}
+
+	match, err := sameSecret(expected, current)
+	if err != nil {
+		return change{}, false, err
+	}
+	return change{path: path, store: name, secret: expected}, match, nil
+}
+
+// repair snapshots the drifted secrets and rewrites them from their artifacts.
+// Unlike a secret message, dependent jobs are left running: the artifact is
+// the value they were last deployed with.
+func (d *DriftDetector) repair(ctx context.Context, changes []change) error {
+	if d.secret.snapshots != nil {
+		name := fmt.Sprintf("drift-%d", time.Now().UTC().Unix())
+		if err := d.secret.snapshot(ctx, name, changes); err != nil {
+			log.Error(ctx, "DriftDetector-repair, d.secret.snapshot() error", err)
+			return err
+		}
+		log.Info(ctx, "snapshotted drifted secrets", log.Data{"snapshot": name})
+	}
+	for _, c := range changes {
+		if err := d.secret.write(ctx, d.secret.stores[c.store], c.path, c.secret); err != nil {
+			log.Error(ctx, "DriftDetector-repair, d.secret.write() error", err, log.Data{"path": c.path, "store": c.store})
+			return err
+		}
+	}
+	return nil
+}
+
+// sameSecret compares the hash of an artifact with the hash of the stored value
+// once both are in canonical JSON form. Stores such as Nomad keep every value as
+// a string, so both sides are converted to items before they are compared.
+func sameSecret(artifact []byte, stored map[string]interface{}) (bool, error) {
+	if len(artifact) == 0 || stored == nil {
+		return len(artifact) == 0 && stored == nil, nil
+	}
+
+	var expected map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(artifact))
+	dec.UseNumber()
+	if err := dec.Decode(&expected); err != nil {
+		return false, err
+	}
+	expectedItems, err := toItems(expected)
+	if err != nil {
+		return false, err
+	}
+	storedItems, err := toItems(stored)
+	if err != nil {
+		return false, err
+	}
+	a, err := json.Marshal(expectedItems)
+	if err != nil {
+		return false, err
+	}
+	b, err := json.Marshal(storedItems)
+	if err != nil {
+		return false, err
+	}
+	return sha256.Sum256(a) == sha256.Sum256(b), nil
+}
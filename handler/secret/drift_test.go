@@ -0,0 +1,146 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	health "github.com/ONSdigital/dp-healthcheck/healthcheck"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/ONSdigital/dp-deployer/config"
+	"github.com/ONSdigital/dp-deployer/s3"
+)
+
+func TestDriftDetector(t *testing.T) {
+	withEnv(func() {
+		Convey("given a drift detector", t, func() {
+			current := map[string]interface{}{"message": "hello world"}
+			store := &SecretStoreMock{
+				ReadFunc:  func(context.Context, string) (map[string]interface{}, error) { return current, nil },
+				WriteFunc: func(context.Context, string, map[string]interface{}) error { return nil },
+			}
+			s3Client := &s3.ClientMock{GetFunc: func(string) (io.ReadCloser, *int64, error) {
+				return io.NopCloser(strings.NewReader(testMessage)), nil, nil
+			}}
+			lister := &ArtifactListerMock{ListFunc: func(string) ([]string, error) {
+				return []string{"secrets/test.json", "snapshots/1.asc", "README.md"}, nil
+			}}
+			restarter := &RestarterMock{RestartDependentsFunc: func(context.Context, string) ([]string, error) {
+				return []string{"job"}, nil
+			}}
+			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, SecretStore: "vault"}, map[string]SecretStore{"vault": store}, s3Client, restarter, nil)
+			So(err, ShouldBeNil)
+			d := NewDriftDetector(s, lister, 0, false)
+
+			Convey("the health check is ok before the first check", func() {
+				state := health.NewCheckState("Secret drift")
+				So(d.Checker(context.Background(), state), ShouldBeNil)
+				So(state.Status(), ShouldEqual, health.StatusOK)
+			})
+
+			Convey("matching secrets have not drifted", func() {
+				drifted, err := d.Check(context.Background())
+				So(err, ShouldBeNil)
+				So(drifted, ShouldBeEmpty)
+				So(s3Client.GetCalls(), ShouldHaveLength, 1)
+				So(store.ReadCalls()[0].Path, ShouldEqual, "test")
+
+				state := health.NewCheckState("Secret drift")
+				So(d.Checker(context.Background(), state), ShouldBeNil)
+				So(state.Status(), ShouldEqual, health.StatusOK)
+			})
+
+			Convey("changed secrets have drifted", func() {
+				current = map[string]interface{}{"message": "goodbye world"}
+				drifted, err := d.Check(context.Background())
+				So(err, ShouldBeNil)
+				So(drifted, ShouldResemble, []string{"test"})
+				So(store.WriteCalls(), ShouldBeEmpty)
+
+				state := health.NewCheckState("Secret drift")
+				So(d.Checker(context.Background(), state), ShouldBeNil)
+				So(state.Status(), ShouldEqual, health.StatusWarning)
+				So(state.Message(), ShouldEqual, "secrets have drifted: test")
+				So(state.Message(), ShouldNotContainSubstring, "world")
+			})
+
+			Convey("missing secrets have drifted", func() {
+				current = nil
+				drifted, err := d.Check(context.Background())
+				So(err, ShouldBeNil)
+				So(drifted, ShouldResemble, []string{"test"})
+			})
+
+			Convey("drifted secrets are rewritten when healing", func() {
+				current = map[string]interface{}{"message": "goodbye world"}
+				d.heal = true
+				drifted, err := d.Check(context.Background())
+				So(err, ShouldBeNil)
+				So(drifted, ShouldBeEmpty)
+				So(store.WriteCalls(), ShouldHaveLength, 1)
+				So(store.WriteCalls()[0].Path, ShouldEqual, "test")
+				So(store.WriteCalls()[0].Data, ShouldResemble, map[string]interface{}{"message": "hello world"})
+				So(restarter.RestartDependentsCalls(), ShouldBeEmpty)
+			})
+
+			Convey("unreadable artifacts are recorded and the rest are still checked", func() {
+				lister.ListFunc = func(string) ([]string, error) {
+					return []string{"secrets/broken.json", "secrets/test.json"}, nil
+				}
+				s3Client.GetFunc = func(key string) (io.ReadCloser, *int64, error) {
+					if key == "secrets/broken.json" {
+						return nil, nil, errors.New("access denied")
+					}
+					return io.NopCloser(strings.NewReader(testMessage)), nil, nil
+				}
+				current = map[string]interface{}{"message": "goodbye world"}
+				drifted, err := d.Check(context.Background())
+				So(err, ShouldBeNil)
+				So(drifted, ShouldResemble, []string{"test"})
+
+				state := health.NewCheckState("Secret drift")
+				So(d.Checker(context.Background(), state), ShouldBeNil)
+				So(state.Status(), ShouldEqual, health.StatusWarning)
+				So(state.Message(), ShouldEqual, "secrets have drifted: test; artifacts could not be checked: secrets/broken.json")
+			})
+
+			Convey("check errors are reported to the health check", func() {
+				lister.ListFunc = func(string) ([]string, error) { return nil, errors.New("access denied") }
+				_, err := d.Check(context.Background())
+				So(err, ShouldNotBeNil)
+
+				state := health.NewCheckState("Secret drift")
+				So(d.Checker(context.Background(), state), ShouldBeNil)
+				So(state.Status(), ShouldEqual, health.StatusWarning)
+			})
+		})
+	})
+}
+
+func TestSameSecret(t *testing.T) {
+	Convey("secrets with non-string values are compared as expected", t, func() {
+		artifact := []byte(`{"count": 2, "enabled": true, "tags": ["a", "b"], "name": "test"}`)
+
+		Convey("values stringified by the store match", func() {
+			match, err := sameSecret(artifact, map[string]interface{}{"count": "2", "enabled": "true", "tags": `["a","b"]`, "name": "test"})
+			So(err, ShouldBeNil)
+			So(match, ShouldBeTrue)
+		})
+
+		Convey("typed values kept by the store match", func() {
+			match, err := sameSecret(artifact, map[string]interface{}{"count": json.Number("2"), "enabled": true, "tags": []interface{}{"a", "b"}, "name": "test"})
+			So(err, ShouldBeNil)
+			So(match, ShouldBeTrue)
+		})
+
+		Convey("changed values do not match", func() {
+			match, err := sameSecret(artifact, map[string]interface{}{"count": "3", "enabled": "true", "tags": `["a","b"]`, "name": "test"})
+			So(err, ShouldBeNil)
+			So(match, ShouldBeFalse)
+		})
+	})
+}
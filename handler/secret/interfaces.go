@@ -1,8 +1,44 @@
 package secret
 
+import (
+	"context"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
 //go:generate moq -out vaultmock_test.go . VaultClient
+//go:generate moq -out storemock_test.go . SecretStore
+//go:generate moq -out restartermock_test.go . Restarter
+//go:generate moq -out snapshotmock_test.go . SnapshotStore
+//go:generate moq -out listermock_test.go . ArtifactLister
 
 // VaultClient is an interface to represent methods called to action upon Vault
 type VaultClient interface {
-	Write(path string, data map[string]interface{}) error
+	ReadWithContext(ctx context.Context, path string) (*vaultapi.Secret, error)
+	WriteWithContext(ctx context.Context, path string, data map[string]interface{}) (*vaultapi.Secret, error)
+	DeleteWithContext(ctx context.Context, path string) (*vaultapi.Secret, error)
+}
+
+// SecretStore is an interface to represent a backend that secrets are written to
+type SecretStore interface {
+	Write(ctx context.Context, path string, data map[string]interface{}) error
+	Read(ctx context.Context, path string) (map[string]interface{}, error)
+	Delete(ctx context.Context, path string) error
+	ListVersions(ctx context.Context, path string) ([]Version, error)
+}
+
+// Restarter is an interface to represent restarting the jobs that depend on a secret
+type Restarter interface {
+	RestartDependents(ctx context.Context, path string) ([]string, error)
+}
+
+// SnapshotStore is an interface to represent where encrypted secret snapshots are kept
+type SnapshotStore interface {
+	Put(name string, snapshot []byte) error
+	Get(name string) ([]byte, error)
+}
+
+// ArtifactLister is an interface to represent listing the encrypted secret artifacts
+type ArtifactLister interface {
+	List(prefix string) ([]string, error)
 }
@@ -0,0 +1,141 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ONSdigital/dp-deployer/nomadclient"
+	nomad "github.com/ONSdigital/dp-nomad"
+)
+
+const variableURL = "%s/v1/var/%s/%s"
+
+// NomadStore is a SecretStore that keeps secrets in Nomad Variables, so that
+// jobs using workload identity can read them without Vault.
+type NomadStore struct {
+	client *nomad.Client
+	prefix string
+	token  nomadclient.TokenSource
+}
+
+type variable struct {
+	Path        string
+	Items       map[string]string
+	ModifyIndex uint64 `json:",omitempty"`
+	ModifyTime  int64  `json:",omitempty"`
+}
+
+// NewNomadStore returns a new nomad store. Secrets are written to variables
+// under the given prefix, e.g. nomad/jobs/<path>, with tokens from the source.
+func NewNomadStore(client *nomad.Client, token nomadclient.TokenSource, prefix string) *NomadStore {
+	return &NomadStore{
+		client: client,
+		prefix: prefix,
+		token:  token,
+	}
+}
+
+// Write writes the secret to a nomad variable. Nomad variables only hold
+// strings, so any other values are stored as JSON.
+func (n *NomadStore) Write(ctx context.Context, path string, data map[string]interface{}) error {
+	items, err := toItems(data)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(variable{Path: n.pathFor(path), Items: items})
+	if err != nil {
+		return err
+	}
+	_, err = n.do(ctx, http.MethodPut, path, b, nil)
+	return err
+}
+
+// Read reads the secret from a nomad variable, returning nil if it does not exist.
+func (n *NomadStore) Read(ctx context.Context, path string) (map[string]interface{}, error) {
+	var v variable
+	found, err := n.do(ctx, http.MethodGet, path, nil, &v)
+	if err != nil || !found {
+		return nil, err
+	}
+	data := make(map[string]interface{}, len(v.Items))
+	for k, item := range v.Items {
+		data[k] = item
+	}
+	return data, nil
+}
+
+// Delete deletes the nomad variable.
+func (n *NomadStore) Delete(ctx context.Context, path string) error {
+	_, err := n.do(ctx, http.MethodDelete, path, nil, nil)
+	return err
+}
+
+// ListVersions lists the versions of the secret. Nomad only keeps the latest
+// version of a variable, which is identified by its modify index.
+func (n *NomadStore) ListVersions(ctx context.Context, path string) ([]Version, error) {
+	var v variable
+	found, err := n.do(ctx, http.MethodGet, path, nil, &v)
+	if err != nil || !found {
+		return nil, err
+	}
+	return []Version{{Version: int(v.ModifyIndex), CreatedAt: time.Unix(0, v.ModifyTime)}}, nil
+}
+
+func (n *NomadStore) pathFor(path string) string {
+	return fmt.Sprintf("%s/%s", n.prefix, path)
+}
+
+func (n *NomadStore) do(ctx context.Context, method, path string, body []byte, v interface{}) (bool, error) {
+	token, err := n.token.Token(ctx)
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf(variableURL, n.client.URL, n.prefix, path), bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Nomad-Token", token)
+
+	res, err := n.client.Client.Do(ctx, req)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return false, err
+	}
+	if res.StatusCode == http.StatusNotFound && method == http.MethodGet {
+		return false, nil
+	}
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return false, &ClientResponseError{Body: string(b), StatusCode: res.StatusCode, URL: req.URL.String()}
+	}
+	if v == nil || len(b) == 0 {
+		return true, nil
+	}
+	return true, json.Unmarshal(b, v)
+}
+
+func toItems(data map[string]interface{}) (map[string]string, error) {
+	items := make(map[string]string, len(data))
+	for k, v := range data {
+		if s, ok := v.(string); ok {
+			items[k] = s
+			continue
+		}
+		j, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		items[k] = string(j)
+	}
+	return items, nil
+}
@@ -0,0 +1,81 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ONSdigital/dp-deployer/nomadclient"
+	dpnethttp "github.com/ONSdigital/dp-net/v2/http"
+	nomad "github.com/ONSdigital/dp-nomad"
+	"github.com/jarcoal/httpmock"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const (
+	nomadURL    = "http://localhost:4646"
+	variableAPI = nomadURL + "/v1/var/nomad/jobs/test"
+)
+
+func TestNomadStore(t *testing.T) {
+	httpmock.Activate()
+	defer httpmock.DeactivateAndReset()
+
+	client := dpnethttp.DefaultClient
+	client.HTTPClient = http.DefaultClient
+	client.MaxRetries = 1
+	n := NewNomadStore(&nomad.Client{Client: client, URL: nomadURL}, nomadclient.StaticToken("token"), "nomad/jobs")
+	ctx := context.Background()
+
+	Convey("the nomad store functions as expected", t, func() {
+		httpmock.Reset()
+
+		Convey("secrets are written as variable items", func() {
+			var body variable
+			httpmock.RegisterResponder("PUT", variableAPI, func(req *http.Request) (*http.Response, error) {
+				b, _ := io.ReadAll(req.Body)
+				json.Unmarshal(b, &body)
+				So(req.Header.Get("X-Nomad-Token"), ShouldEqual, "token")
+				return httpmock.NewStringResponse(200, `{}`), nil
+			})
+
+			err := n.Write(ctx, "test", map[string]interface{}{"foo": "bar", "port": 8080, "enabled": true})
+			So(err, ShouldBeNil)
+			So(body.Path, ShouldEqual, "nomad/jobs/test")
+			So(body.Items, ShouldResemble, map[string]string{"foo": "bar", "port": "8080", "enabled": "true"})
+		})
+
+		Convey("write errors are returned", func() {
+			httpmock.RegisterResponder("PUT", variableAPI, httpmock.NewStringResponder(403, "Permission denied"))
+			err := n.Write(ctx, "test", map[string]interface{}{"foo": "bar"})
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "unexpected response from client")
+		})
+
+		Convey("secrets are read from variable items", func() {
+			httpmock.RegisterResponder("GET", variableAPI, httpmock.NewStringResponder(200, `{"Path": "nomad/jobs/test", "Items": {"foo": "bar"}, "ModifyIndex": 42, "ModifyTime": 1700000000000000000}`))
+			d, err := n.Read(ctx, "test")
+			So(err, ShouldBeNil)
+			So(d, ShouldResemble, map[string]interface{}{"foo": "bar"})
+
+			versions, err := n.ListVersions(ctx, "test")
+			So(err, ShouldBeNil)
+			So(versions, ShouldHaveLength, 1)
+			So(versions[0].Version, ShouldEqual, 42)
+		})
+
+		Convey("missing variables are read as nil", func() {
+			httpmock.RegisterResponder("GET", variableAPI, httpmock.NewStringResponder(404, "variable not found"))
+			d, err := n.Read(ctx, "test")
+			So(err, ShouldBeNil)
+			So(d, ShouldBeNil)
+		})
+
+		Convey("variables are deleted", func() {
+			httpmock.RegisterResponder("DELETE", variableAPI, httpmock.NewStringResponder(200, ""))
+			So(n.Delete(ctx, "test"), ShouldBeNil)
+		})
+	})
+}
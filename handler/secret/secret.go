@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
 	"strings"
 
@@ -27,29 +26,84 @@ func (e *AbortedError) Error() string {
 	return "aborted updating secrets for message"
 }
 
+// ClientResponseError is an error implementation that includes the body and status
+// code of the response.
+type ClientResponseError struct {
+	Body       string
+	StatusCode int
+	URL        string
+}
+
+func (e *ClientResponseError) Error() string {
+	return "unexpected response from client"
+}
+
+// UnknownStoreError is an error implementation that includes the name of the
+// secret store that could not be found.
+type UnknownStoreError struct {
+	Name string
+}
+
+func (e *UnknownStoreError) Error() string {
+	return "unknown secret store"
+}
+
 // Secret represents a secret.
 type Secret struct {
-	entities openpgp.EntityList
-	s3Client s3.Client
-	vault    VaultClient
+	entities     openpgp.EntityList
+	s3Client     s3.Client
+	stores       map[string]SecretStore
+	defaultStore string
+	rules        map[string]string
+	restarter    Restarter
+	snapshots    SnapshotStore
 }
 
-// New returns a new secret.
-func New(cfg *config.Configuration, vc VaultClient, secretsClient s3.Client) (*Secret, error) {
+// Result represents the result of handling a secret message.
+type Result struct {
+	Snapshot  string              `json:",omitempty"`
+	Restarted map[string][]string `json:",omitempty"`
+}
+
+type change struct {
+	path   string
+	store  string
+	secret []byte
+}
+
+// New returns a new secret. Secrets are written to the store named by the
+// message, then by the longest matching path rule, then by the default store.
+// If a restarter is given, the jobs depending on each secret are restarted
+// once it has been written. If a snapshot store is given, the previous value
+// of every secret is saved to it before anything is written.
+func New(cfg *config.Configuration, stores map[string]SecretStore, secretsClient s3.Client, restarter Restarter, snapshots SnapshotStore) (*Secret, error) {
 	e, err := entityList(cfg.PrivateKey)
 	if err != nil {
 		return nil, err
 	}
+	if _, ok := stores[cfg.SecretStore]; !ok {
+		return nil, &UnknownStoreError{Name: cfg.SecretStore}
+	}
+	for _, name := range cfg.SecretStoreRules {
+		if _, ok := stores[name]; !ok {
+			return nil, &UnknownStoreError{Name: name}
+		}
+	}
 
 	return &Secret{
-		entities: e,
-		s3Client: secretsClient,
-		vault:    vc,
+		entities:     e,
+		s3Client:     secretsClient,
+		stores:       stores,
+		defaultStore: cfg.SecretStore,
+		rules:        cfg.SecretStoreRules,
+		restarter:    restarter,
+		snapshots:    snapshots,
 	}, nil
 }
 
 // Handler handles secret messages that are delegated by the engine.
 func (s *Secret) Handler(ctx context.Context, msg *engine.Message) error {
+	var changes []change
 	for _, artifact := range msg.Artifacts {
 		select {
 		case <-ctx.Done():
@@ -71,11 +125,58 @@ func (s *Secret) Handler(ctx context.Context, msg *engine.Message) error {
 				log.Error(ctx, "Secret-Handler, s.decryptMessage(b) error", err)
 				return err
 			}
-			log.Info(ctx, "writing secret", log.Data{"artifact": artifact})
-			if err := s.write(pathFor(artifact), d); err != nil {
-				log.Error(ctx, "Secret-Handler, s.write(pathFor) error", err)
+			name := s.storeName(msg.Store, pathFor(artifact))
+			if _, ok := s.stores[name]; !ok {
+				err := &UnknownStoreError{Name: name}
+				log.Error(ctx, "Secret-Handler, s.storeName() error", err)
 				return err
 			}
+			changes = append(changes, change{path: pathFor(artifact), store: name, secret: d})
+		}
+	}
+	return s.apply(ctx, msg, changes, false)
+}
+
+// apply snapshots the current value of each changed secret and then writes
+// the changes. If deletes are allowed, any secret whose new value is empty is
+// deleted, otherwise writing it fails.
+func (s *Secret) apply(ctx context.Context, msg *engine.Message, changes []change, deletes bool) error {
+	result := &Result{}
+	if s.snapshots != nil {
+		if err := s.snapshot(ctx, msg.ID, changes); err != nil {
+			log.Error(ctx, "Secret-apply, s.snapshot() error", err)
+			return err
+		}
+		result.Snapshot = msg.ID
+		msg.Result = result
+	}
+
+	for _, c := range changes {
+		store := s.stores[c.store]
+		log.Info(ctx, "writing secret", log.Data{"path": c.path, "store": c.store})
+		if deletes && len(c.secret) == 0 {
+			if err := store.Delete(ctx, c.path); err != nil {
+				log.Error(ctx, "Secret-apply, store.Delete() error", err)
+				return err
+			}
+		} else if err := s.write(ctx, store, c.path, c.secret); err != nil {
+			log.Error(ctx, "Secret-apply, s.write(pathFor) error", err)
+			return err
+		}
+		if s.restarter == nil {
+			continue
+		}
+		restarted, err := s.restarter.RestartDependents(ctx, c.path)
+		if len(restarted) > 0 {
+			if result.Restarted == nil {
+				result.Restarted = make(map[string][]string)
+			}
+			result.Restarted[c.path] = restarted
+			msg.Result = result
+		}
+		if err != nil {
+			log.Error(ctx, "Secret-apply, s.restarter.RestartDependents() error", err)
+			return err
 		}
 	}
 	return nil
@@ -100,19 +201,33 @@ func (s *Secret) decryptMessage(message io.Reader) ([]byte, error) {
 	return d, nil
 }
 
-func (s *Secret) write(path string, secret []byte) error {
+func (s *Secret) write(ctx context.Context, store SecretStore, path string, secret []byte) error {
 	var j map[string]interface{}
 	if err := json.Unmarshal(secret, &j); err != nil {
-		log.Error(context.Background(), "Secret-write, json.Unmarshal() error", err)
+		log.Error(ctx, "Secret-write, json.Unmarshal() error", err)
 		return err
 	}
-	if err := s.vault.Write(fmt.Sprintf("secret/%s", path), j); err != nil {
-		log.Error(context.Background(), "Secret-write, s.vault.Write() error", err)
+	if err := store.Write(ctx, path, j); err != nil {
+		log.Error(ctx, "Secret-write, store.Write() error", err)
 		return err
 	}
 	return nil
 }
 
+func (s *Secret) storeName(name, path string) string {
+	if len(name) > 0 {
+		return name
+	}
+	name = s.defaultStore
+	matched := ""
+	for prefix, rule := range s.rules {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(matched) {
+			matched, name = prefix, rule
+		}
+	}
+	return name
+}
+
 func pathFor(artifact string) string {
 	return strings.Split(strings.Split(artifact, "/")[1], ".")[0]
 }
@@ -91,7 +91,7 @@ Z/E5hX0lDHzAklyBHfVeUdarqA==
 func TestNew(t *testing.T) {
 
 	Convey("an error is returned with an invalid private key", t, func() {
-		s, err := New(&config.Configuration{PrivateKey: "", AWSRegion: "foo"}, &VaultClientMock{}, &s3.ClientMock{})
+		s, err := New(&config.Configuration{PrivateKey: "", AWSRegion: "foo", SecretStore: "vault"}, map[string]SecretStore{"vault": &SecretStoreMock{}}, &s3.ClientMock{}, nil, nil)
 		So(s, ShouldBeNil)
 		So(err, ShouldNotBeNil)
 		So(err.Error(), ShouldEqual, io.EOF.Error())
@@ -99,7 +99,7 @@ func TestNew(t *testing.T) {
 
 	withEnv(func() {
 		Convey("a handler is returned with valid configuration", t, func() {
-			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "bar"}, &VaultClientMock{}, &s3.ClientMock{})
+			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "bar", SecretStore: "vault"}, map[string]SecretStore{"vault": &SecretStoreMock{}}, &s3.ClientMock{}, nil, nil)
 			So(err, ShouldBeNil)
 			So(s, ShouldNotBeNil)
 		})
@@ -109,7 +109,7 @@ func TestNew(t *testing.T) {
 func TestEntity(t *testing.T) {
 	withEnv(func() {
 		Convey("successfully creates openpgp entity", t, func() {
-			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "foo"}, &VaultClientMock{}, &s3.ClientMock{})
+			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "foo", SecretStore: "vault"}, map[string]SecretStore{"vault": &SecretStoreMock{}}, &s3.ClientMock{}, nil, nil)
 			So(err, ShouldBeNil)
 			So(s, ShouldNotBeNil)
 
@@ -124,7 +124,7 @@ func TestEntity(t *testing.T) {
 func TestDearmor(t *testing.T) {
 	withEnv(func() {
 		Convey("successfully strips armor", t, func() {
-			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "eu-west-1"}, &VaultClientMock{}, &s3.ClientMock{})
+			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "eu-west-1", SecretStore: "vault"}, map[string]SecretStore{"vault": &SecretStoreMock{}}, &s3.ClientMock{}, nil, nil)
 			So(err, ShouldBeNil)
 			So(s, ShouldNotBeNil)
 
@@ -140,7 +140,7 @@ func TestDearmor(t *testing.T) {
 func TestDecrypt(t *testing.T) {
 	withEnv(func() {
 		Convey("successfully decrypts message", t, func() {
-			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "eu-west-1"}, &VaultClientMock{}, &s3.ClientMock{})
+			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "eu-west-1", SecretStore: "vault"}, map[string]SecretStore{"vault": &SecretStoreMock{}}, &s3.ClientMock{}, nil, nil)
 			So(err, ShouldBeNil)
 			So(s, ShouldNotBeNil)
 
@@ -155,8 +155,11 @@ func TestDecrypt(t *testing.T) {
 func TestWriteFails(t *testing.T) {
 	withEnv(func() {
 		Convey("given a failure writing to vault", t, func() {
-			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "eu-west-1"},
-				&VaultClientMock{WriteFunc: func(string, map[string]interface{}) error { return errors.New("Error making API request") }}, &s3.ClientMock{})
+			store := &SecretStoreMock{WriteFunc: func(context.Context, string, map[string]interface{}) error {
+				return errors.New("Error making API request")
+			}}
+			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "eu-west-1", SecretStore: "vault"},
+				map[string]SecretStore{"vault": store}, &s3.ClientMock{}, nil, nil)
 			So(err, ShouldBeNil)
 			So(s, ShouldNotBeNil)
 
@@ -165,7 +168,7 @@ func TestWriteFails(t *testing.T) {
 			So(m, ShouldNotBeNil)
 
 			Convey("handles error correctly", func() {
-				err := s.write("test", m)
+				err := s.write(context.Background(), store, "test", m)
 				So(err, ShouldNotBeNil)
 				So(err.Error(), ShouldStartWith, "Error making API request")
 			})
@@ -176,8 +179,9 @@ func TestWriteFails(t *testing.T) {
 func TestWrite(t *testing.T) {
 	withEnv(func() {
 		Convey("write functions as expected", t, func() {
-			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "eu-west-1"},
-				&VaultClientMock{WriteFunc: func(string, map[string]interface{}) error { return nil }}, &s3.ClientMock{})
+			store := &SecretStoreMock{WriteFunc: func(context.Context, string, map[string]interface{}) error { return nil }}
+			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "eu-west-1", SecretStore: "vault"},
+				map[string]SecretStore{"vault": store}, &s3.ClientMock{}, nil, nil)
 			So(err, ShouldBeNil)
 			So(s, ShouldNotBeNil)
 
@@ -186,8 +190,92 @@ func TestWrite(t *testing.T) {
 			So(m, ShouldNotBeNil)
 
 			Convey("writes secret correctly", func() {
-				err := s.write("test", m)
+				err := s.write(context.Background(), store, "test", m)
 				So(err, ShouldBeNil)
+				So(store.WriteCalls(), ShouldHaveLength, 1)
+				So(store.WriteCalls()[0].Path, ShouldEqual, "test")
+			})
+		})
+	})
+}
+
+func TestStoreName(t *testing.T) {
+	withEnv(func() {
+		Convey("the secret store is selected as expected", t, func() {
+			vaultStore, nomadStore := &SecretStoreMock{}, &SecretStoreMock{}
+			stores := map[string]SecretStore{"vault": vaultStore, "nomad": nomadStore}
+			cfg := &config.Configuration{
+				PrivateKey:       testPrivateKey,
+				SecretStore:      "vault",
+				SecretStoreRules: map[string]string{"dp-": "nomad", "dp-api": "vault"},
+			}
+			s, err := New(cfg, stores, &s3.ClientMock{}, nil, nil)
+			So(err, ShouldBeNil)
+
+			Convey("the default store is used when no rule matches", func() {
+				So(s.storeName("", "babbage"), ShouldEqual, "vault")
+			})
+
+			Convey("the longest matching path rule is used", func() {
+				So(s.storeName("", "dp-frontend-router"), ShouldEqual, "nomad")
+				So(s.storeName("", "dp-api-router"), ShouldEqual, "vault")
+			})
+
+			Convey("the store named by the message takes precedence", func() {
+				So(s.storeName("nomad", "babbage"), ShouldEqual, "nomad")
+			})
+
+			Convey("an unknown store named by the message returns an error", func() {
+				s.s3Client = &s3.ClientMock{GetFunc: func(string) (io.ReadCloser, *int64, error) {
+					return io.NopCloser(strings.NewReader(testMessage)), nil, nil
+				}}
+				err := s.Handler(context.Background(), &engine.Message{Artifacts: []string{"secrets/test.json"}, Store: "consul"})
+				So(err, ShouldResemble, &UnknownStoreError{Name: "consul"})
+				So(vaultStore.WriteCalls(), ShouldBeEmpty)
+				So(nomadStore.WriteCalls(), ShouldBeEmpty)
+			})
+		})
+
+		Convey("an error is returned when the default store is unknown", t, func() {
+			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, SecretStore: "nomad"}, map[string]SecretStore{"vault": &SecretStoreMock{}}, &s3.ClientMock{}, nil, nil)
+			So(s, ShouldBeNil)
+			So(err, ShouldResemble, &UnknownStoreError{Name: "nomad"})
+		})
+	})
+}
+
+func TestRestartDependents(t *testing.T) {
+	withEnv(func() {
+		Convey("given a restarter for dependent jobs", t, func() {
+			store := &SecretStoreMock{WriteFunc: func(context.Context, string, map[string]interface{}) error { return nil }}
+			s3Client := &s3.ClientMock{GetFunc: func(string) (io.ReadCloser, *int64, error) {
+				return io.NopCloser(strings.NewReader(testMessage)), nil, nil
+			}}
+
+			Convey("the restarted allocations are reported in the result", func() {
+				restarter := &RestarterMock{RestartDependentsFunc: func(context.Context, string) ([]string, error) { return []string{"alloc-1"}, nil }}
+				s, err := New(&config.Configuration{PrivateKey: testPrivateKey, SecretStore: "vault"}, map[string]SecretStore{"vault": store}, s3Client, restarter, nil)
+				So(err, ShouldBeNil)
+
+				msg := &engine.Message{Artifacts: []string{"secrets/test.json"}}
+				err = s.Handler(context.Background(), msg)
+				So(err, ShouldBeNil)
+				So(restarter.RestartDependentsCalls()[0].Path, ShouldEqual, "test")
+				So(msg.Result, ShouldResemble, &Result{Restarted: map[string][]string{"test": {"alloc-1"}}})
+			})
+
+			Convey("restart errors are returned", func() {
+				restarter := &RestarterMock{RestartDependentsFunc: func(context.Context, string) ([]string, error) {
+					return nil, errors.New("allocation failed to restart")
+				}}
+				s, err := New(&config.Configuration{PrivateKey: testPrivateKey, SecretStore: "vault"}, map[string]SecretStore{"vault": store}, s3Client, restarter, nil)
+				So(err, ShouldBeNil)
+
+				msg := &engine.Message{Artifacts: []string{"secrets/test.json"}}
+				err = s.Handler(context.Background(), msg)
+				So(err, ShouldNotBeNil)
+				So(msg.Result, ShouldBeNil)
+				So(store.WriteCalls(), ShouldHaveLength, 1)
 			})
 		})
 	})
@@ -196,7 +284,7 @@ func TestWrite(t *testing.T) {
 func TestContext(t *testing.T) {
 	withEnv(func() {
 		Convey("handler functions as expected when context is cancelled", t, func() {
-			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "eu-west-1"}, &VaultClientMock{}, &s3.ClientMock{})
+			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, AWSRegion: "eu-west-1", SecretStore: "vault"}, map[string]SecretStore{"vault": &SecretStoreMock{}}, &s3.ClientMock{}, nil, nil)
 			So(err, ShouldBeNil)
 			So(s, ShouldNotBeNil)
 
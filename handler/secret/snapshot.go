@@ -0,0 +1,230 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/s3"
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+const snapshotKey = "snapshots/%s.asc"
+
+var (
+	// ErrMissingSnapshot is returned when a restore message does not name a snapshot.
+	ErrMissingSnapshot = errors.New("missing snapshot name")
+	// ErrMissingSnapshotStore is returned when restoring without a snapshot store.
+	ErrMissingSnapshotStore = errors.New("missing snapshot store")
+)
+
+// InvalidSnapshotError is an error implementation that includes the rejected
+// snapshot name.
+type InvalidSnapshotError struct {
+	Name string
+}
+
+func (e *InvalidSnapshotError) Error() string {
+	return "invalid snapshot name"
+}
+
+// snapshotPath returns the key the named snapshot is stored under. Names that
+// are empty or could escape the snapshot prefix are rejected.
+func snapshotPath(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", &InvalidSnapshotError{Name: name}
+	}
+	return fmt.Sprintf(snapshotKey, name), nil
+}
+
+// Snapshot represents the values of a set of secrets before they were changed.
+type Snapshot struct {
+	Name    string
+	Created time.Time
+	Secrets []SnapshotSecret
+}
+
+// SnapshotSecret represents the value of a single secret in a snapshot. An
+// empty value means the secret did not exist.
+type SnapshotSecret struct {
+	Path   string
+	Store  string
+	Secret json.RawMessage `json:",omitempty"`
+}
+
+// FileSnapshotStore is a SnapshotStore that keeps snapshots in a local directory.
+type FileSnapshotStore struct {
+	dir string
+}
+
+// NewFileSnapshotStore returns a new file snapshot store.
+func NewFileSnapshotStore(dir string) *FileSnapshotStore {
+	return &FileSnapshotStore{dir: dir}
+}
+
+// Put writes the snapshot to the directory.
+func (f *FileSnapshotStore) Put(name string, snapshot []byte) error {
+	key, err := snapshotPath(name)
+	if err != nil {
+		return err
+	}
+	p := filepath.Join(f.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(p, snapshot, 0600)
+}
+
+// Get reads the snapshot from the directory.
+func (f *FileSnapshotStore) Get(name string) ([]byte, error) {
+	key, err := snapshotPath(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filepath.Join(f.dir, filepath.FromSlash(key)))
+}
+
+// S3SnapshotStore is a SnapshotStore that keeps snapshots in an S3 bucket.
+type S3SnapshotStore struct {
+	client s3.Uploader
+}
+
+// NewS3SnapshotStore returns a new S3 snapshot store.
+func NewS3SnapshotStore(client s3.Uploader) *S3SnapshotStore {
+	return &S3SnapshotStore{client: client}
+}
+
+// Put uploads the snapshot to the bucket.
+func (s *S3SnapshotStore) Put(name string, snapshot []byte) error {
+	key, err := snapshotPath(name)
+	if err != nil {
+		return err
+	}
+	bucket := s.client.BucketName()
+	_, err = s.client.Upload(&s3manager.UploadInput{
+		Bucket: &bucket,
+		Key:    &key,
+		Body:   bytes.NewReader(snapshot),
+	})
+	return err
+}
+
+// Get downloads the snapshot from the bucket.
+func (s *S3SnapshotStore) Get(name string) ([]byte, error) {
+	key, err := snapshotPath(name)
+	if err != nil {
+		return nil, err
+	}
+	b, _, err := s.client.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close()
+	return io.ReadAll(b)
+}
+
+// RestoreHandler handles secret-restore messages that are delegated by the
+// engine. The secrets in the named snapshot are put back to the stores they
+// were taken from, and the values they replace are snapshotted in turn.
+func (s *Secret) RestoreHandler(ctx context.Context, msg *engine.Message) error {
+	if s.snapshots == nil {
+		log.Error(ctx, "Secret-RestoreHandler, no snapshot store configured", ErrMissingSnapshotStore)
+		return ErrMissingSnapshotStore
+	}
+	if len(msg.Snapshot) < 1 {
+		log.Error(ctx, "Secret-RestoreHandler, missing snapshot", ErrMissingSnapshot)
+		return ErrMissingSnapshot
+	}
+
+	b, err := s.snapshots.Get(msg.Snapshot)
+	if err != nil {
+		log.Error(ctx, "Secret-RestoreHandler, s.snapshots.Get() error", err)
+		return err
+	}
+	d, err := s.decryptMessage(bytes.NewReader(b))
+	if err != nil {
+		log.Error(ctx, "Secret-RestoreHandler, s.decryptMessage() error", err)
+		return err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(d, &snapshot); err != nil {
+		log.Error(ctx, "Secret-RestoreHandler, json.Unmarshal() error", err)
+		return err
+	}
+
+	log.Info(ctx, "restoring snapshot", log.Data{"snapshot": snapshot.Name, "created": snapshot.Created})
+	var changes []change
+	for _, secret := range snapshot.Secrets {
+		if _, ok := s.stores[secret.Store]; !ok {
+			err := &UnknownStoreError{Name: secret.Store}
+			log.Error(ctx, "Secret-RestoreHandler, unknown store error", err)
+			return err
+		}
+		changes = append(changes, change{path: secret.Path, store: secret.Store, secret: secret.Secret})
+	}
+	return s.apply(ctx, msg, changes, true)
+}
+
+// snapshot reads the current value of each changed secret and saves them to
+// the snapshot store, encrypted to the deployer key.
+func (s *Secret) snapshot(ctx context.Context, name string, changes []change) error {
+	snapshot := Snapshot{Name: name, Created: time.Now().UTC()}
+	for _, c := range changes {
+		current, err := s.stores[c.store].Read(ctx, c.path)
+		if err != nil {
+			return err
+		}
+		secret := SnapshotSecret{Path: c.path, Store: c.store}
+		if current != nil {
+			if secret.Secret, err = json.Marshal(current); err != nil {
+				return err
+			}
+		}
+		snapshot.Secrets = append(snapshot.Secrets, secret)
+	}
+
+	j, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	b, err := s.encryptMessage(j)
+	if err != nil {
+		return err
+	}
+	log.Info(ctx, "saving secret snapshot", log.Data{"snapshot": name, "secrets": len(snapshot.Secrets)})
+	return s.snapshots.Put(name, b)
+}
+
+func (s *Secret) encryptMessage(message []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	a, err := armor.Encode(&buf, "PGP MESSAGE", nil)
+	if err != nil {
+		return nil, err
+	}
+	w, err := openpgp.Encrypt(a, s.entities, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(message); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := a.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
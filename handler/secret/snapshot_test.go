@@ -0,0 +1,170 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/ONSdigital/dp-deployer/config"
+	"github.com/ONSdigital/dp-deployer/engine"
+	"github.com/ONSdigital/dp-deployer/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+func TestSnapshot(t *testing.T) {
+	withEnv(func() {
+		Convey("given a secret handler with a snapshot store", t, func() {
+			store := &SecretStoreMock{
+				ReadFunc: func(ctx context.Context, path string) (map[string]interface{}, error) {
+					if path == "test" {
+						return map[string]interface{}{"message": "goodbye world"}, nil
+					}
+					return nil, nil
+				},
+				WriteFunc:  func(context.Context, string, map[string]interface{}) error { return nil },
+				DeleteFunc: func(context.Context, string) error { return nil },
+			}
+			snapshots := map[string][]byte{}
+			snapshotStore := &SnapshotStoreMock{
+				PutFunc: func(name string, snapshot []byte) error {
+					snapshots[name] = snapshot
+					return nil
+				},
+				GetFunc: func(name string) ([]byte, error) { return snapshots[name], nil },
+			}
+			s3Client := &s3.ClientMock{GetFunc: func(string) (io.ReadCloser, *int64, error) {
+				return io.NopCloser(strings.NewReader(testMessage)), nil, nil
+			}}
+			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, SecretStore: "vault"}, map[string]SecretStore{"vault": store}, s3Client, nil, snapshotStore)
+			So(err, ShouldBeNil)
+
+			readSnapshot := func(name string) Snapshot {
+				d, err := s.decryptMessage(bytes.NewReader(snapshots[name]))
+				So(err, ShouldBeNil)
+				var snapshot Snapshot
+				So(json.Unmarshal(d, &snapshot), ShouldBeNil)
+				return snapshot
+			}
+
+			Convey("the previous values are snapshotted before writing", func() {
+				msg := &engine.Message{ID: "1", Artifacts: []string{"secrets/test.json", "secrets/new.json"}}
+				err := s.Handler(context.Background(), msg)
+				So(err, ShouldBeNil)
+				So(msg.Result, ShouldResemble, &Result{Snapshot: "1"})
+				So(store.WriteCalls(), ShouldHaveLength, 2)
+
+				snapshot := readSnapshot("1")
+				So(snapshot.Name, ShouldEqual, "1")
+				So(snapshot.Secrets, ShouldHaveLength, 2)
+				So(snapshot.Secrets[0].Path, ShouldEqual, "test")
+				So(snapshot.Secrets[0].Store, ShouldEqual, "vault")
+				So(string(snapshot.Secrets[0].Secret), ShouldEqual, `{"message":"goodbye world"}`)
+				So(snapshot.Secrets[1].Path, ShouldEqual, "new")
+				So(snapshot.Secrets[1].Secret, ShouldBeEmpty)
+			})
+
+			Convey("nothing is written when the snapshot fails", func() {
+				snapshotStore.PutFunc = func(string, []byte) error { return errors.New("access denied") }
+				err := s.Handler(context.Background(), &engine.Message{ID: "1", Artifacts: []string{"secrets/test.json"}})
+				So(err, ShouldNotBeNil)
+				So(store.WriteCalls(), ShouldBeEmpty)
+			})
+
+			Convey("a snapshot is restored", func() {
+				So(s.Handler(context.Background(), &engine.Message{ID: "1", Artifacts: []string{"secrets/test.json", "secrets/new.json"}}), ShouldBeNil)
+
+				msg := &engine.Message{ID: "2", Snapshot: "1"}
+				err := s.RestoreHandler(context.Background(), msg)
+				So(err, ShouldBeNil)
+				So(msg.Result, ShouldResemble, &Result{Snapshot: "2"})
+
+				writes := store.WriteCalls()
+				So(writes, ShouldHaveLength, 3)
+				So(writes[2].Path, ShouldEqual, "test")
+				So(writes[2].Data, ShouldResemble, map[string]interface{}{"message": "goodbye world"})
+				So(store.DeleteCalls(), ShouldHaveLength, 1)
+				So(store.DeleteCalls()[0].Path, ShouldEqual, "new")
+				So(readSnapshot("2").Secrets, ShouldHaveLength, 2)
+			})
+
+			Convey("empty secrets are only deleted by a restore", func() {
+				err := s.apply(context.Background(), &engine.Message{ID: "1"}, []change{{path: "new", store: "vault"}}, false)
+				So(err, ShouldNotBeNil)
+				So(store.DeleteCalls(), ShouldBeEmpty)
+				So(store.WriteCalls(), ShouldBeEmpty)
+			})
+
+			Convey("a restore without a snapshot name is refused", func() {
+				err := s.RestoreHandler(context.Background(), &engine.Message{ID: "2"})
+				So(err, ShouldEqual, ErrMissingSnapshot)
+			})
+		})
+
+		Convey("a restore without a snapshot store is refused", t, func() {
+			s, err := New(&config.Configuration{PrivateKey: testPrivateKey, SecretStore: "vault"}, map[string]SecretStore{"vault": &SecretStoreMock{}}, &s3.ClientMock{}, nil, nil)
+			So(err, ShouldBeNil)
+			err = s.RestoreHandler(context.Background(), &engine.Message{ID: "2", Snapshot: "1"})
+			So(err, ShouldEqual, ErrMissingSnapshotStore)
+		})
+	})
+}
+
+func TestFileSnapshotStore(t *testing.T) {
+	Convey("snapshots are saved to and read from the directory", t, func() {
+		f := NewFileSnapshotStore(t.TempDir())
+		So(f.Put("1", []byte("snapshot")), ShouldBeNil)
+
+		b, err := f.Get("1")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "snapshot")
+
+		_, err = f.Get("2")
+		So(err, ShouldNotBeNil)
+
+		for _, name := range []string{"", "..", "../1", "a/b", `a\b`} {
+			So(f.Put(name, []byte("snapshot")), ShouldResemble, &InvalidSnapshotError{Name: name})
+			_, err = f.Get(name)
+			So(err, ShouldResemble, &InvalidSnapshotError{Name: name})
+		}
+	})
+}
+
+func TestS3SnapshotStore(t *testing.T) {
+	Convey("snapshots are uploaded to and read from the bucket", t, func() {
+		var uploaded []byte
+		client := &s3.UploaderMock{
+			BucketNameFunc: func() string { return "bucket" },
+			UploadFunc: func(input *s3manager.UploadInput, options ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+				uploaded, _ = io.ReadAll(input.Body)
+				return &s3manager.UploadOutput{}, nil
+			},
+			GetFunc: func(key string) (io.ReadCloser, *int64, error) {
+				return io.NopCloser(bytes.NewReader(uploaded)), nil, nil
+			},
+		}
+		store := NewS3SnapshotStore(client)
+		So(store.Put("1", []byte("snapshot")), ShouldBeNil)
+		So(*client.UploadCalls()[0].Input.Key, ShouldEqual, "snapshots/1.asc")
+
+		b, err := store.Get("1")
+		So(err, ShouldBeNil)
+		So(string(b), ShouldEqual, "snapshot")
+		So(client.GetCalls()[0].Key, ShouldEqual, "snapshots/1.asc")
+
+		Convey("names that could escape the snapshot prefix are rejected", func() {
+			for _, name := range []string{"", "..", "../secrets/1", "a/b", `a\b`} {
+				So(store.Put(name, []byte("snapshot")), ShouldResemble, &InvalidSnapshotError{Name: name})
+				_, err := store.Get(name)
+				So(err, ShouldResemble, &InvalidSnapshotError{Name: name})
+			}
+			So(client.UploadCalls(), ShouldHaveLength, 1)
+			So(client.GetCalls(), ShouldHaveLength, 1)
+		})
+	})
+}
@@ -0,0 +1,164 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+const (
+	vaultMountPath    = "sys/internal/ui/mounts/secret"
+	vaultPath         = "secret/%s"
+	vaultDataPath     = "secret/data/%s"
+	vaultMetadataPath = "secret/metadata/%s"
+)
+
+// Version represents a single version of a secret held by a store.
+type Version struct {
+	Version   int
+	CreatedAt time.Time
+	Deleted   bool
+}
+
+// VaultStore is a SecretStore that keeps secrets in Vault under secret/<path>,
+// with the paths of a KV version 1 or 2 engine to match the secret mount.
+type VaultStore struct {
+	client  VaultClient
+	mu      sync.Mutex
+	version int
+}
+
+// NewVaultStore returns a new vault store.
+func NewVaultStore(vc VaultClient) *VaultStore {
+	return &VaultStore{client: vc}
+}
+
+// kvVersion returns the version of the KV engine at the secret mount, which is
+// read from vault the first time it is needed. Mounts without a version, or
+// that the token cannot see, are treated as version 1.
+func (v *VaultStore) kvVersion(ctx context.Context) (int, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.version > 0 {
+		return v.version, nil
+	}
+
+	s, err := v.client.ReadWithContext(ctx, vaultMountPath)
+	var re *vaultapi.ResponseError
+	if errors.As(err, &re) && re.StatusCode == http.StatusForbidden {
+		s, err = nil, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	v.version = 1
+	if s != nil {
+		if options, ok := s.Data["options"].(map[string]interface{}); ok && options["version"] == "2" {
+			v.version = 2
+		}
+	}
+	return v.version, nil
+}
+
+// Write writes the secret to vault.
+func (v *VaultStore) Write(ctx context.Context, path string, data map[string]interface{}) error {
+	version, err := v.kvVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if version == 2 {
+		_, err = v.client.WriteWithContext(ctx, fmt.Sprintf(vaultDataPath, path), map[string]interface{}{"data": data})
+		return err
+	}
+	_, err = v.client.WriteWithContext(ctx, fmt.Sprintf(vaultPath, path), data)
+	return err
+}
+
+// Read reads the secret from vault, returning nil if it does not exist.
+func (v *VaultStore) Read(ctx context.Context, path string) (map[string]interface{}, error) {
+	version, err := v.kvVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version == 1 {
+		s, err := v.client.ReadWithContext(ctx, fmt.Sprintf(vaultPath, path))
+		if err != nil || s == nil {
+			return nil, err
+		}
+		return s.Data, nil
+	}
+
+	s, err := v.client.ReadWithContext(ctx, fmt.Sprintf(vaultDataPath, path))
+	if err != nil || s == nil {
+		return nil, err
+	}
+	// The data of a deleted version is nil.
+	data, _ := s.Data["data"].(map[string]interface{})
+	return data, nil
+}
+
+// Delete deletes the secret from vault. A KV version 2 engine keeps the
+// deleted version's metadata.
+func (v *VaultStore) Delete(ctx context.Context, path string) error {
+	version, err := v.kvVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if version == 2 {
+		_, err = v.client.DeleteWithContext(ctx, fmt.Sprintf(vaultDataPath, path))
+		return err
+	}
+	_, err = v.client.DeleteWithContext(ctx, fmt.Sprintf(vaultPath, path))
+	return err
+}
+
+// ListVersions lists the versions of the secret. Versions are only kept by a
+// KV version 2 engine, so an empty list is returned for version 1 mounts.
+func (v *VaultStore) ListVersions(ctx context.Context, path string) ([]Version, error) {
+	version, err := v.kvVersion(ctx)
+	if err != nil || version == 1 {
+		return nil, err
+	}
+
+	s, err := v.client.ReadWithContext(ctx, fmt.Sprintf(vaultMetadataPath, path))
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, nil
+	}
+
+	raw, ok := s.Data["versions"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	var versions []Version
+	for k, m := range raw {
+		n, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, err
+		}
+		meta, _ := m.(map[string]interface{})
+		version := Version{Version: n}
+		if created, ok := meta["created_time"].(string); ok {
+			version.CreatedAt, _ = time.Parse(time.RFC3339Nano, created)
+		}
+		if deleted, ok := meta["deletion_time"].(string); ok && deleted != "" {
+			version.Deleted = true
+		}
+		if destroyed, ok := meta["destroyed"].(bool); ok && destroyed {
+			version.Deleted = true
+		}
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	return versions, nil
+}
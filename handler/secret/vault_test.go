@@ -0,0 +1,179 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVaultStore(t *testing.T) {
+	ctx := context.Background()
+
+	Convey("the vault store functions as expected for a KV version 1 mount", t, func() {
+		var read *vaultapi.Secret
+		vc := &VaultClientMock{
+			ReadWithContextFunc: func(ctx context.Context, path string) (*vaultapi.Secret, error) {
+				if path == vaultMountPath {
+					return &vaultapi.Secret{Data: map[string]interface{}{"options": map[string]interface{}{"version": "1"}}}, nil
+				}
+				return read, nil
+			},
+			WriteWithContextFunc:  func(context.Context, string, map[string]interface{}) (*vaultapi.Secret, error) { return nil, nil },
+			DeleteWithContextFunc: func(context.Context, string) (*vaultapi.Secret, error) { return nil, nil },
+		}
+		v := NewVaultStore(vc)
+
+		Convey("secrets are written under the secret mount", func() {
+			err := v.Write(ctx, "test", map[string]interface{}{"foo": "bar"})
+			So(err, ShouldBeNil)
+			So(vc.WriteWithContextCalls(), ShouldHaveLength, 1)
+			So(vc.WriteWithContextCalls()[0].Path, ShouldEqual, "secret/test")
+			So(vc.WriteWithContextCalls()[0].Data, ShouldResemble, map[string]interface{}{"foo": "bar"})
+		})
+
+		Convey("secrets are read under the secret mount", func() {
+			read = &vaultapi.Secret{Data: map[string]interface{}{"foo": "bar"}}
+			d, err := v.Read(ctx, "test")
+			So(err, ShouldBeNil)
+			So(vc.ReadWithContextCalls()[1].Path, ShouldEqual, "secret/test")
+			So(d, ShouldResemble, map[string]interface{}{"foo": "bar"})
+		})
+
+		Convey("secrets are deleted under the secret mount", func() {
+			err := v.Delete(ctx, "test")
+			So(err, ShouldBeNil)
+			So(vc.DeleteWithContextCalls()[0].Path, ShouldEqual, "secret/test")
+		})
+
+		Convey("missing secrets are read as nil", func() {
+			d, err := v.Read(ctx, "test")
+			So(err, ShouldBeNil)
+			So(d, ShouldBeNil)
+		})
+
+		Convey("no versions are listed", func() {
+			versions, err := v.ListVersions(ctx, "test")
+			So(err, ShouldBeNil)
+			So(versions, ShouldBeEmpty)
+			So(vc.ReadWithContextCalls(), ShouldHaveLength, 1)
+		})
+
+		Convey("the mount version is only read once", func() {
+			So(v.Write(ctx, "test", map[string]interface{}{"foo": "bar"}), ShouldBeNil)
+			So(v.Delete(ctx, "test"), ShouldBeNil)
+			So(vc.ReadWithContextCalls(), ShouldHaveLength, 1)
+		})
+	})
+
+	Convey("the vault store functions as expected for a KV version 2 mount", t, func() {
+		var read *vaultapi.Secret
+		vc := &VaultClientMock{
+			ReadWithContextFunc: func(ctx context.Context, path string) (*vaultapi.Secret, error) {
+				if path == vaultMountPath {
+					return &vaultapi.Secret{Data: map[string]interface{}{"options": map[string]interface{}{"version": "2"}}}, nil
+				}
+				return read, nil
+			},
+			WriteWithContextFunc:  func(context.Context, string, map[string]interface{}) (*vaultapi.Secret, error) { return nil, nil },
+			DeleteWithContextFunc: func(context.Context, string) (*vaultapi.Secret, error) { return nil, nil },
+		}
+		v := NewVaultStore(vc)
+
+		Convey("secrets are written under the data path", func() {
+			err := v.Write(ctx, "test", map[string]interface{}{"foo": "bar"})
+			So(err, ShouldBeNil)
+			So(vc.WriteWithContextCalls(), ShouldHaveLength, 1)
+			So(vc.WriteWithContextCalls()[0].Path, ShouldEqual, "secret/data/test")
+			So(vc.WriteWithContextCalls()[0].Data, ShouldResemble, map[string]interface{}{"data": map[string]interface{}{"foo": "bar"}})
+		})
+
+		Convey("secrets are read from the data path", func() {
+			read = &vaultapi.Secret{Data: map[string]interface{}{"data": map[string]interface{}{"foo": "bar"}}}
+			d, err := v.Read(ctx, "test")
+			So(err, ShouldBeNil)
+			So(vc.ReadWithContextCalls()[1].Path, ShouldEqual, "secret/data/test")
+			So(d, ShouldResemble, map[string]interface{}{"foo": "bar"})
+		})
+
+		Convey("deleted secrets are read as nil", func() {
+			read = &vaultapi.Secret{Data: map[string]interface{}{"data": nil}}
+			d, err := v.Read(ctx, "test")
+			So(err, ShouldBeNil)
+			So(d, ShouldBeNil)
+		})
+
+		Convey("secrets are deleted under the data path", func() {
+			err := v.Delete(ctx, "test")
+			So(err, ShouldBeNil)
+			So(vc.DeleteWithContextCalls()[0].Path, ShouldEqual, "secret/data/test")
+		})
+
+		Convey("versions are listed in order from the metadata", func() {
+			read = &vaultapi.Secret{}
+			So(json.Unmarshal([]byte(`{"data": {"versions": {
+				"2": {"created_time": "2024-01-02T00:00:00Z", "deletion_time": "", "destroyed": false},
+				"1": {"created_time": "2024-01-01T00:00:00Z", "deletion_time": "2024-01-02T00:00:00Z", "destroyed": false}
+			}}}`), read), ShouldBeNil)
+
+			versions, err := v.ListVersions(ctx, "test")
+			So(err, ShouldBeNil)
+			So(vc.ReadWithContextCalls()[1].Path, ShouldEqual, "secret/metadata/test")
+			So(versions, ShouldHaveLength, 2)
+			So(versions[0].Version, ShouldEqual, 1)
+			So(versions[0].Deleted, ShouldBeTrue)
+			So(versions[1].Version, ShouldEqual, 2)
+			So(versions[1].Deleted, ShouldBeFalse)
+		})
+
+		Convey("no versions are listed for a missing secret", func() {
+			versions, err := v.ListVersions(ctx, "test")
+			So(err, ShouldBeNil)
+			So(versions, ShouldBeEmpty)
+		})
+	})
+
+	Convey("a mount the token cannot see is treated as KV version 1", t, func() {
+		vc := &VaultClientMock{
+			ReadWithContextFunc: func(ctx context.Context, path string) (*vaultapi.Secret, error) {
+				return nil, &vaultapi.ResponseError{StatusCode: 403, Errors: []string{"permission denied"}}
+			},
+			WriteWithContextFunc: func(context.Context, string, map[string]interface{}) (*vaultapi.Secret, error) { return nil, nil },
+		}
+		v := NewVaultStore(vc)
+
+		So(v.Write(ctx, "test", map[string]interface{}{"foo": "bar"}), ShouldBeNil)
+		So(vc.WriteWithContextCalls()[0].Path, ShouldEqual, "secret/test")
+		versions, err := v.ListVersions(ctx, "test")
+		So(err, ShouldBeNil)
+		So(versions, ShouldBeEmpty)
+		So(vc.ReadWithContextCalls(), ShouldHaveLength, 1)
+	})
+
+	Convey("errors reading the mount version are returned and retried", t, func() {
+		fail := true
+		vc := &VaultClientMock{
+			ReadWithContextFunc: func(ctx context.Context, path string) (*vaultapi.Secret, error) {
+				if fail {
+					return nil, errors.New("Error making API request")
+				}
+				return nil, nil
+			},
+			WriteWithContextFunc: func(context.Context, string, map[string]interface{}) (*vaultapi.Secret, error) { return nil, nil },
+		}
+		v := NewVaultStore(vc)
+
+		d, err := v.Read(ctx, "test")
+		So(err, ShouldNotBeNil)
+		So(d, ShouldBeNil)
+		So(v.Write(ctx, "test", map[string]interface{}{"foo": "bar"}), ShouldNotBeNil)
+		So(vc.WriteWithContextCalls(), ShouldBeEmpty)
+
+		fail = false
+		So(v.Write(ctx, "test", map[string]interface{}{"foo": "bar"}), ShouldBeNil)
+		So(vc.WriteWithContextCalls()[0].Path, ShouldEqual, "secret/test")
+	})
+}
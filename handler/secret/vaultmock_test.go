@@ -4,11 +4,16 @@
 package secret
 
 import (
+	"context"
 	"sync"
+
+	vaultapi "github.com/hashicorp/vault/api"
 )
 
 var (
-	lockVaultClientMockWrite sync.RWMutex
+	lockVaultClientMockReadWithContext   sync.RWMutex
+	lockVaultClientMockWriteWithContext  sync.RWMutex
+	lockVaultClientMockDeleteWithContext sync.RWMutex
 )
 
 // Ensure, that VaultClientMock does implement VaultClient.
@@ -21,8 +26,14 @@ var _ VaultClient = &VaultClientMock{}
 //
 //	        // make and configure a mocked VaultClient
 //	        mockedVaultClient := &VaultClientMock{
-//	            WriteFunc: func(path string, data map[string]interface{}) error {
-//		               panic("mock out the Write method")
+//	            ReadWithContextFunc: func(ctx context.Context, path string) (*vaultapi.Secret, error) {
+//		               panic("mock out the ReadWithContext method")
+//	            },
+//	            WriteWithContextFunc: func(ctx context.Context, path string, data map[string]interface{}) (*vaultapi.Secret, error) {
+//		               panic("mock out the WriteWithContext method")
+//	            },
+//	            DeleteWithContextFunc: func(ctx context.Context, path string) (*vaultapi.Secret, error) {
+//		               panic("mock out the DeleteWithContext method")
 //	            },
 //	        }
 //
@@ -31,53 +42,151 @@ var _ VaultClient = &VaultClientMock{}
 //
 //	    }
 type VaultClientMock struct {
-	// WriteFunc mocks the Write method.
-	WriteFunc func(path string, data map[string]interface{}) error
+	// ReadWithContextFunc mocks the ReadWithContext method.
+	ReadWithContextFunc func(ctx context.Context, path string) (*vaultapi.Secret, error)
+
+	// WriteWithContextFunc mocks the WriteWithContext method.
+	WriteWithContextFunc func(ctx context.Context, path string, data map[string]interface{}) (*vaultapi.Secret, error)
+
+	// DeleteWithContextFunc mocks the DeleteWithContext method.
+	DeleteWithContextFunc func(ctx context.Context, path string) (*vaultapi.Secret, error)
 
 	// calls tracks calls to the methods.
 	calls struct {
-		// Write holds details about calls to the Write method.
-		Write []struct {
+		// ReadWithContext holds details about calls to the ReadWithContext method.
+		ReadWithContext []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Path is the path argument value.
+			Path string
+		}
+		// WriteWithContext holds details about calls to the WriteWithContext method.
+		WriteWithContext []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
 			// Path is the path argument value.
 			Path string
 			// Data is the data argument value.
 			Data map[string]interface{}
 		}
+		// DeleteWithContext holds details about calls to the DeleteWithContext method.
+		DeleteWithContext []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Path is the path argument value.
+			Path string
+		}
 	}
 }
 
-// Write calls WriteFunc.
-func (mock *VaultClientMock) Write(path string, data map[string]interface{}) error {
-	if mock.WriteFunc == nil {
-		panic("VaultClientMock.WriteFunc: method is nil but VaultClient.Write was just called")
+// ReadWithContext calls ReadWithContextFunc.
+func (mock *VaultClientMock) ReadWithContext(ctx context.Context, path string) (*vaultapi.Secret, error) {
+	if mock.ReadWithContextFunc == nil {
+		panic("VaultClientMock.ReadWithContextFunc: method is nil but VaultClient.ReadWithContext was just called")
 	}
 	callInfo := struct {
+		Ctx  context.Context
+		Path string
+	}{
+		Ctx:  ctx,
+		Path: path,
+	}
+	lockVaultClientMockReadWithContext.Lock()
+	mock.calls.ReadWithContext = append(mock.calls.ReadWithContext, callInfo)
+	lockVaultClientMockReadWithContext.Unlock()
+	return mock.ReadWithContextFunc(ctx, path)
+}
+
+// ReadWithContextCalls gets all the calls that were made to ReadWithContext.
+// Check the length with:
+//
+//	len(mockedVaultClient.ReadWithContextCalls())
+func (mock *VaultClientMock) ReadWithContextCalls() []struct {
+	Ctx  context.Context
+	Path string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Path string
+	}
+	lockVaultClientMockReadWithContext.RLock()
+	calls = mock.calls.ReadWithContext
+	lockVaultClientMockReadWithContext.RUnlock()
+	return calls
+}
+
+// WriteWithContext calls WriteWithContextFunc.
+func (mock *VaultClientMock) WriteWithContext(ctx context.Context, path string, data map[string]interface{}) (*vaultapi.Secret, error) {
+	if mock.WriteWithContextFunc == nil {
+		panic("VaultClientMock.WriteWithContextFunc: method is nil but VaultClient.WriteWithContext was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
 		Path string
 		Data map[string]interface{}
 	}{
+		Ctx:  ctx,
 		Path: path,
 		Data: data,
 	}
-	lockVaultClientMockWrite.Lock()
-	mock.calls.Write = append(mock.calls.Write, callInfo)
-	lockVaultClientMockWrite.Unlock()
-	return mock.WriteFunc(path, data)
+	lockVaultClientMockWriteWithContext.Lock()
+	mock.calls.WriteWithContext = append(mock.calls.WriteWithContext, callInfo)
+	lockVaultClientMockWriteWithContext.Unlock()
+	return mock.WriteWithContextFunc(ctx, path, data)
 }
 
-// WriteCalls gets all the calls that were made to Write.
+// WriteWithContextCalls gets all the calls that were made to WriteWithContext.
 // Check the length with:
 //
-//	len(mockedVaultClient.WriteCalls())
-func (mock *VaultClientMock) WriteCalls() []struct {
+//	len(mockedVaultClient.WriteWithContextCalls())
+func (mock *VaultClientMock) WriteWithContextCalls() []struct {
+	Ctx  context.Context
 	Path string
 	Data map[string]interface{}
 } {
 	var calls []struct {
+		Ctx  context.Context
 		Path string
 		Data map[string]interface{}
 	}
-	lockVaultClientMockWrite.RLock()
-	calls = mock.calls.Write
-	lockVaultClientMockWrite.RUnlock()
+	lockVaultClientMockWriteWithContext.RLock()
+	calls = mock.calls.WriteWithContext
+	lockVaultClientMockWriteWithContext.RUnlock()
+	return calls
+}
+
+// DeleteWithContext calls DeleteWithContextFunc.
+func (mock *VaultClientMock) DeleteWithContext(ctx context.Context, path string) (*vaultapi.Secret, error) {
+	if mock.DeleteWithContextFunc == nil {
+		panic("VaultClientMock.DeleteWithContextFunc: method is nil but VaultClient.DeleteWithContext was just called")
+	}
+	callInfo := struct {
+		Ctx  context.Context
+		Path string
+	}{
+		Ctx:  ctx,
+		Path: path,
+	}
+	lockVaultClientMockDeleteWithContext.Lock()
+	mock.calls.DeleteWithContext = append(mock.calls.DeleteWithContext, callInfo)
+	lockVaultClientMockDeleteWithContext.Unlock()
+	return mock.DeleteWithContextFunc(ctx, path)
+}
+
+// DeleteWithContextCalls gets all the calls that were made to DeleteWithContext.
+// Check the length with:
+//
+//	len(mockedVaultClient.DeleteWithContextCalls())
+func (mock *VaultClientMock) DeleteWithContextCalls() []struct {
+	Ctx  context.Context
+	Path string
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Path string
+	}
+	lockVaultClientMockDeleteWithContext.RLock()
+	calls = mock.calls.DeleteWithContext
+	lockVaultClientMockDeleteWithContext.RUnlock()
 	return calls
 }
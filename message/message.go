@@ -2,13 +2,30 @@ package message
 
 // MessageSQS represents a message that has been consumed.
 type MessageSQS struct {
+	ID          string `json:"-"`
 	Job         string
+	Type        string       `json:"type,omitempty"`
 	Java        bool         `json:"java,omitempty"`
 	Go          bool         `json:"go,omitempty"`
 	Publishing  *Groups      `json:"publishing,omitempty"`
 	Web         *Groups      `json:"web,omitempty"`
 	Healthcheck *Healthcheck `json:"healthcheck,omitempty"`
 	Revision    string
+	Canary      int               `json:"canary,omitempty"`
+	Override    bool              `json:"override,omitempty"`
+	Cluster     string            `json:"cluster,omitempty"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Region      string            `json:"region,omitempty"`
+	Datacenters []string          `json:"datacenters,omitempty"`
+	MessageID   string            `json:"message_id,omitempty"`
+	Purge       bool              `json:"purge,omitempty"`
+	Group       string            `json:"group,omitempty"`
+	Count       *int              `json:"count,omitempty"`
+	ForceRun    bool              `json:"force_run,omitempty"`
+	Meta        map[string]string `json:"meta,omitempty"`
+	Payload     string            `json:"payload,omitempty"`
+	Signer      string            `json:"-"`
+	Result      interface{}       `json:"-"`
 }
 
 // Groups represents the publishing or web group for the MessageSQS
@@ -14,10 +14,9 @@ import (
 
 // CreateJob Creates the Nomad job structure for an application deployment
 func CreateJob(ctx context.Context, cfg *config.Configuration, name string, jobStruct *message.MessageSQS) api.Job {
-	region := "eu"
 	jobType := "service"
 
-	updateStrategy := createUpdateStrategy(jobStruct.Java)
+	updateStrategy := createUpdateStrategy(jobStruct.Java, jobStruct.Canary)
 	var taskGroups []*api.TaskGroup
 
 	if jobStruct.Publishing != nil {
@@ -31,17 +30,33 @@ func CreateJob(ctx context.Context, cfg *config.Configuration, name string, jobS
 
 	job := api.Job{
 		Name:        &name,
-		Region:      &region,
-		Datacenters: []string{"eu-west-1"},
+		Datacenters: cfg.NomadDatacenters,
 		Type:        &jobType,
 		Update:      &updateStrategy,
 		TaskGroups:  taskGroups,
 	}
+	if len(jobStruct.Datacenters) > 0 {
+		job.Datacenters = jobStruct.Datacenters
+	}
+	if namespace := target(jobStruct.Namespace, cfg.NomadNamespace); len(namespace) > 0 {
+		job.Namespace = &namespace
+	}
+	if region := target(jobStruct.Region, cfg.NomadRegion); len(region) > 0 {
+		job.Region = &region
+	}
 
 	return job
 }
 
-func createUpdateStrategy(isJava bool) api.UpdateStrategy {
+// target returns the message's value if it is set, otherwise the configured one.
+func target(msgValue, cfgValue string) string {
+	if len(msgValue) > 0 {
+		return msgValue
+	}
+	return cfgValue
+}
+
+func createUpdateStrategy(isJava bool, canary int) api.UpdateStrategy {
 	healthyTime := time.Second * 30
 	healthyDeadline := time.Minute * 2
 	maxParallel := 1
@@ -60,6 +75,13 @@ func createUpdateStrategy(isJava bool) api.UpdateStrategy {
 		AutoRevert:      &autorevert,
 	}
 
+	// Canaries are promoted by the deployer once they are healthy
+	if canary > 0 {
+		autoPromote := false
+		updateStrategy.Canary = &canary
+		updateStrategy.AutoPromote = &autoPromote
+	}
+
 	return updateStrategy
 }
 
@@ -0,0 +1,366 @@
+// Package nomadclient provides a typed client for the Nomad HTTP API that
+// retries transient errors of reads and idempotent calls.
+package nomadclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ONSdigital/log.go/v2/log"
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	jobsURL        = "%s/v1/jobs"
+	parseURL       = "%s/v1/jobs/parse"
+	jobURL         = "%s/v1/job/%s"
+	versionsURL    = "%s/v1/job/%s/versions"
+	planURL        = "%s/v1/job/%s/plan"
+	revertURL      = "%s/v1/job/%s/revert"
+	scaleURL       = "%s/v1/job/%s/scale"
+	forceURL       = "%s/v1/job/%s/periodic/force"
+	dispatchURL    = "%s/v1/job/%s/dispatch"
+	deploymentsURL = "%s/v1/job/%s/deployments"
+	allocationsURL = "%s/v1/job/%s/allocations"
+	promoteURL     = "%s/v1/deployment/promote/%s"
+	failURL        = "%s/v1/deployment/fail/%s"
+	allocationURL  = "%s/v1/allocation/%s"
+	restartURL     = "%s/v1/client/allocation/%s/restart"
+	checksURL      = "%s/v1/client/allocation/%s/checks"
+	logsURL        = "%s/v1/client/fs/logs/%s?task=%s&type=%s&origin=end&offset=%d&plain=true"
+	evaluationURL  = "%s/v1/evaluation/%s"
+	healthURL      = "%s/v1/agent/health?type=client"
+)
+
+// Doer is the interface of the HTTP client requests are made with, which is
+// satisfied by a dp-net Clienter.
+type Doer interface {
+	Do(ctx context.Context, req *http.Request) (*http.Response, error)
+}
+
+// HTTPClient is a Client for the Nomad HTTP API.
+type HTTPClient struct {
+	doer      Doer
+	endpoint  string
+	token     TokenSource
+	namespace string
+	region    string
+	retries   int
+	backoff   time.Duration
+}
+
+// New returns a client for the Nomad API at the endpoint that authorises its
+// requests with the token. Transient errors are retried up to the given number
+// of times, waiting for the backoff before the first retry and twice as long
+// before each one after. The doer should not retry requests itself.
+func New(endpoint, token string, doer Doer, retries int, backoff time.Duration) *HTTPClient {
+	return &HTTPClient{doer: doer, endpoint: endpoint, token: StaticToken(token), retries: retries, backoff: backoff}
+}
+
+// WithToken returns a copy of the client that authorises its requests with
+// tokens from the source instead.
+func (c *HTTPClient) WithToken(token TokenSource) Client {
+	t := *c
+	t.token = token
+	return &t
+}
+
+// Target returns a copy of the client that makes its requests to the namespace
+// and region, where they are set, instead of the agent's.
+func (c *HTTPClient) Target(namespace, region string) Client {
+	t := *c
+	if len(namespace) > 0 {
+		t.namespace = namespace
+	}
+	if len(region) > 0 {
+		t.region = region
+	}
+	return &t
+}
+
+// ListJobs returns the jobs.
+func (c *HTTPClient) ListJobs(ctx context.Context) ([]api.JobListStub, error) {
+	var jobs []api.JobListStub
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf(jobsURL, c.endpoint), nil, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// GetJob returns the job.
+func (c *HTTPClient) GetJob(ctx context.Context, jobID string) (*api.Job, error) {
+	var job api.Job
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf(jobURL, c.endpoint, url.PathEscape(jobID)), nil, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetJobVersions returns the versions of the job, newest first.
+func (c *HTTPClient) GetJobVersions(ctx context.Context, jobID string) (*api.JobVersionsResponse, error) {
+	var versions api.JobVersionsResponse
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf(versionsURL, c.endpoint, url.PathEscape(jobID)), nil, &versions); err != nil {
+		return nil, err
+	}
+	return &versions, nil
+}
+
+// ParseJob parses a HCL2 jobspec into a job.
+func (c *HTTPClient) ParseJob(ctx context.Context, req *api.JobsParseRequest) (*api.Job, error) {
+	var job api.Job
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf(parseURL, c.endpoint), req, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// PlanJob plans the job without registering it, including its diff if asked.
+func (c *HTTPClient) PlanJob(ctx context.Context, jobID string, job *api.Job, diff bool) (*api.JobPlanResponse, error) {
+	var res api.JobPlanResponse
+	if err := c.do(ctx, http.MethodPost, fmt.Sprintf(planURL, c.endpoint, url.PathEscape(jobID)), &api.JobPlanRequest{Job: job, Diff: diff}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// RegisterJob registers the job.
+func (c *HTTPClient) RegisterJob(ctx context.Context, job *api.Job) (*api.JobRegisterResponse, error) {
+	var res api.JobRegisterResponse
+	if err := c.doOnce(ctx, http.MethodPost, fmt.Sprintf(jobsURL, c.endpoint), &api.JobRegisterRequest{Job: job}, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// RevertJob reverts the job to an earlier version.
+func (c *HTTPClient) RevertJob(ctx context.Context, req *api.JobRevertRequest) (*api.JobRegisterResponse, error) {
+	var res api.JobRegisterResponse
+	if err := c.doOnce(ctx, http.MethodPost, fmt.Sprintf(revertURL, c.endpoint, url.PathEscape(req.JobID)), req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// DeregisterJob stops the job, and purges it from Nomad if asked.
+func (c *HTTPClient) DeregisterJob(ctx context.Context, jobID string, purge bool) (*api.JobDeregisterResponse, error) {
+	rawURL := fmt.Sprintf(jobURL, c.endpoint, url.PathEscape(jobID))
+	if purge {
+		rawURL += "?purge=true"
+	}
+	var res api.JobDeregisterResponse
+	if err := c.do(ctx, http.MethodDelete, rawURL, nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ScaleJob sets the count of the job's task group.
+func (c *HTTPClient) ScaleJob(ctx context.Context, jobID, group string, count int, message string) (*api.JobRegisterResponse, error) {
+	n := int64(count)
+	req := &api.ScalingRequest{Count: &n, Target: map[string]string{"Job": jobID, "Group": group}, Message: message}
+	var res api.JobRegisterResponse
+	if err := c.doOnce(ctx, http.MethodPost, fmt.Sprintf(scaleURL, c.endpoint, url.PathEscape(jobID)), req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ForcePeriodic launches a child job of the periodic job now and returns the
+// ID of its evaluation.
+func (c *HTTPClient) ForcePeriodic(ctx context.Context, jobID string) (string, error) {
+	var res struct{ EvalID string }
+	if err := c.doOnce(ctx, http.MethodPost, fmt.Sprintf(forceURL, c.endpoint, url.PathEscape(jobID)), nil, &res); err != nil {
+		return "", err
+	}
+	return res.EvalID, nil
+}
+
+// DispatchJob dispatches a child job of the parameterized job with the meta and
+// payload.
+func (c *HTTPClient) DispatchJob(ctx context.Context, jobID string, meta map[string]string, payload []byte) (*api.JobDispatchResponse, error) {
+	req := &api.JobDispatchRequest{JobID: jobID, Meta: meta, Payload: payload}
+	var res api.JobDispatchResponse
+	if err := c.doOnce(ctx, http.MethodPost, fmt.Sprintf(dispatchURL, c.endpoint, url.PathEscape(jobID)), req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ListDeployments returns the deployments of the job.
+func (c *HTTPClient) ListDeployments(ctx context.Context, jobID string) ([]api.Deployment, error) {
+	var deployments []api.Deployment
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf(deploymentsURL, c.endpoint, url.PathEscape(jobID)), nil, &deployments); err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
+// PromoteDeployment promotes all the canaries of the deployment.
+func (c *HTTPClient) PromoteDeployment(ctx context.Context, deploymentID string) error {
+	req := &api.DeploymentPromoteRequest{DeploymentID: deploymentID, All: true}
+	return c.doOnce(ctx, http.MethodPost, fmt.Sprintf(promoteURL, c.endpoint, deploymentID), req, nil)
+}
+
+// FailDeployment marks the deployment as failed.
+func (c *HTTPClient) FailDeployment(ctx context.Context, deploymentID string) error {
+	return c.doOnce(ctx, http.MethodPost, fmt.Sprintf(failURL, c.endpoint, deploymentID), nil, nil)
+}
+
+// ListAllocations returns the allocations of the job.
+func (c *HTTPClient) ListAllocations(ctx context.Context, jobID string) ([]api.AllocationListStub, error) {
+	var allocations []api.AllocationListStub
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf(allocationsURL, c.endpoint, url.PathEscape(jobID)), nil, &allocations); err != nil {
+		return nil, err
+	}
+	return allocations, nil
+}
+
+// GetAllocation returns the allocation.
+func (c *HTTPClient) GetAllocation(ctx context.Context, allocationID string) (*api.Allocation, error) {
+	var allocation api.Allocation
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf(allocationURL, c.endpoint, allocationID), nil, &allocation); err != nil {
+		return nil, err
+	}
+	return &allocation, nil
+}
+
+// RestartAllocation restarts all the tasks in the allocation.
+func (c *HTTPClient) RestartAllocation(ctx context.Context, allocationID string) error {
+	req := &api.AllocationRestartRequest{AllTasks: true}
+	return c.doOnce(ctx, http.MethodPost, fmt.Sprintf(restartURL, c.endpoint, allocationID), req, nil)
+}
+
+// GetAllocationChecks returns the results of the allocation's Nomad service
+// checks.
+func (c *HTTPClient) GetAllocationChecks(ctx context.Context, allocationID string) (api.AllocCheckStatuses, error) {
+	var checks api.AllocCheckStatuses
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf(checksURL, c.endpoint, allocationID), nil, &checks); err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// TaskLogs returns up to the last tail bytes of the task's stdout or stderr log.
+func (c *HTTPClient) TaskLogs(ctx context.Context, allocationID, task, logType string, tail int64) ([]byte, error) {
+	var b []byte
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf(logsURL, c.endpoint, allocationID, url.QueryEscape(task), logType, tail), nil, &b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// GetEvaluation returns the evaluation.
+func (c *HTTPClient) GetEvaluation(ctx context.Context, evaluationID string) (*api.Evaluation, error) {
+	var evaluation api.Evaluation
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf(evaluationURL, c.endpoint, evaluationID), nil, &evaluation); err != nil {
+		return nil, err
+	}
+	return &evaluation, nil
+}
+
+// Health checks the health of the Nomad client agent. It is not retried, so
+// that health checks report the agent's current state.
+func (c *HTTPClient) Health(ctx context.Context) error {
+	return c.doOnce(ctx, http.MethodGet, fmt.Sprintf(healthURL, c.endpoint), nil, nil)
+}
+
+// do makes the request with the JSON encoded body, if there is one, and
+// decodes the response into v, retrying transient errors with backoff. It is
+// only used for reads and calls that are safe to repeat.
+func (c *HTTPClient) do(ctx context.Context, method, rawURL string, body, v interface{}) error {
+	return c.send(ctx, method, rawURL, body, v, c.retries)
+}
+
+// doOnce makes the request like do but never retries it, for calls that are
+// not idempotent. A transient error may hide a call that Nomad carried out, so
+// repeating it could dispatch, force, restart or register a job twice.
+func (c *HTTPClient) doOnce(ctx context.Context, method, rawURL string, body, v interface{}) error {
+	return c.send(ctx, method, rawURL, body, v, 0)
+}
+
+func (c *HTTPClient) send(ctx context.Context, method, rawURL string, body, v interface{}, retries int) error {
+	var b []byte
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		err := c.try(ctx, method, rawURL, b, v)
+		if attempt == retries || !IsTransient(err) {
+			return err
+		}
+		log.Warn(ctx, "transient nomad error - will retry", log.Data{"method": method, "url": rawURL, "attempt": attempt + 1, "error": err.Error()})
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+func (c *HTTPClient) try(ctx context.Context, method, rawURL string, body []byte, v interface{}) error {
+	token, err := c.token.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Nomad-Token", token)
+	SetTarget(req.URL, c.namespace, c.region)
+
+	res, err := c.doer.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return &ResponseError{Body: string(b), StatusCode: res.StatusCode, URL: req.URL.String()}
+	}
+	if v == nil {
+		return nil
+	}
+	// Plain text responses such as logs are returned as they are.
+	if raw, ok := v.(*[]byte); ok {
+		*raw = b
+		return nil
+	}
+	return json.Unmarshal(b, v)
+}
+
+// SetTarget adds the namespace and region, where they are set, to the query of
+// a Nomad API request.
+func SetTarget(u *url.URL, namespace, region string) {
+	if len(namespace) == 0 && len(region) == 0 {
+		return
+	}
+	q := u.Query()
+	if len(namespace) > 0 {
+		q.Set("namespace", namespace)
+	}
+	if len(region) > 0 {
+		q.Set("region", region)
+	}
+	u.RawQuery = q.Encode()
+}
@@ -0,0 +1,150 @@
+package nomadclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dphttp "github.com/ONSdigital/dp-net/v2/http"
+	"github.com/hashicorp/nomad/api"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// flakyNomad answers requests for a job with a 502 until it has failed the
+// given number of times.
+func flakyNomad(failures int64, requests *int64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt64(requests, 1) <= failures {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		if req.Header.Get("X-Nomad-Token") != "token" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		fmt.Fprintf(w, `{"ID": "test", "Namespace": %q, "Region": %q}`, req.URL.Query().Get("namespace"), req.URL.Query().Get("region"))
+	}))
+}
+
+func newClient(endpoint string, retries int) *HTTPClient {
+	doer := dphttp.NewClient()
+	doer.SetMaxRetries(0)
+	return New(endpoint, "token", doer, retries, time.Millisecond*10)
+}
+
+func TestClient(t *testing.T) {
+	Convey("transient errors are retried with backoff", t, func() {
+		var requests int64
+		s := flakyNomad(2, &requests)
+		defer s.Close()
+
+		job, err := newClient(s.URL, 3).GetJob(context.Background(), "test")
+		So(err, ShouldBeNil)
+		So(*job.ID, ShouldEqual, "test")
+		So(atomic.LoadInt64(&requests), ShouldEqual, 3)
+	})
+
+	Convey("the last error is returned once the retries are used up", t, func() {
+		var requests int64
+		s := flakyNomad(5, &requests)
+		defer s.Close()
+
+		_, err := newClient(s.URL, 2).GetJob(context.Background(), "test")
+		So(err, ShouldHaveSameTypeAs, &ResponseError{})
+		So(err.(*ResponseError).StatusCode, ShouldEqual, http.StatusBadGateway)
+		So(atomic.LoadInt64(&requests), ShouldEqual, 3)
+	})
+
+	Convey("retries stop when the context is cancelled", t, func() {
+		var requests int64
+		s := flakyNomad(5, &requests)
+		defer s.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		c := New(s.URL, "token", newClient(s.URL, 0).doer, 3, time.Second)
+		time.AfterFunc(time.Millisecond*100, cancel)
+		start := time.Now()
+		_, err := c.GetJob(ctx, "test")
+		So(err, ShouldNotBeNil)
+		So(time.Since(start), ShouldBeLessThan, time.Second)
+		So(atomic.LoadInt64(&requests), ShouldEqual, 1)
+	})
+
+	Convey("calls that are not idempotent are not retried", t, func() {
+		var requests int64
+		s := flakyNomad(1, &requests)
+		defer s.Close()
+
+		_, err := newClient(s.URL, 3).DispatchJob(context.Background(), "test", nil, nil)
+		So(err, ShouldHaveSameTypeAs, &ResponseError{})
+		So(err.(*ResponseError).StatusCode, ShouldEqual, http.StatusBadGateway)
+		So(atomic.LoadInt64(&requests), ShouldEqual, 1)
+
+		_, err = newClient(s.URL, 3).RegisterJob(context.Background(), &api.Job{})
+		So(err, ShouldBeNil)
+		So(atomic.LoadInt64(&requests), ShouldEqual, 2)
+	})
+
+	Convey("other errors are not retried", t, func() {
+		var requests int64
+		s := flakyNomad(0, &requests)
+		defer s.Close()
+
+		c := New(s.URL, "wrong", newClient(s.URL, 0).doer, 3, time.Millisecond*10)
+		_, err := c.GetJob(context.Background(), "test")
+		So(err, ShouldHaveSameTypeAs, &ResponseError{})
+		So(err.(*ResponseError).StatusCode, ShouldEqual, http.StatusForbidden)
+		So(atomic.LoadInt64(&requests), ShouldEqual, 1)
+	})
+
+	Convey("targeted clients add the namespace and region to requests", t, func() {
+		var requests int64
+		s := flakyNomad(0, &requests)
+		defer s.Close()
+
+		c := newClient(s.URL, 0)
+		job, err := c.Target("apps", "us").GetJob(context.Background(), "test")
+		So(err, ShouldBeNil)
+		So(*job.Namespace, ShouldEqual, "apps")
+		So(*job.Region, ShouldEqual, "us")
+
+		job, err = c.GetJob(context.Background(), "test")
+		So(err, ShouldBeNil)
+		So(*job.Namespace, ShouldBeEmpty)
+	})
+
+	Convey("the target namespace and region are added to request queries", t, func() {
+		u, _ := url.Parse("http://localhost:4646/v1/job/test/allocations?index=1")
+		SetTarget(u, "dp", "eu")
+		So(u.Query(), ShouldResemble, url.Values{"index": {"1"}, "namespace": {"dp"}, "region": {"eu"}})
+
+		u, _ = url.Parse("http://localhost:4646/v1/jobs")
+		SetTarget(u, "", "")
+		So(u.RawQuery, ShouldBeEmpty)
+	})
+}
+
+func TestErrors(t *testing.T) {
+	Convey("errors are classified", t, func() {
+		So(IsNotFound(&ResponseError{StatusCode: http.StatusNotFound}), ShouldBeTrue)
+		So(IsNotFound(&ResponseError{StatusCode: http.StatusBadGateway}), ShouldBeFalse)
+		So(IsNotFound(errors.New("not found")), ShouldBeFalse)
+
+		So(IsTransient(&ResponseError{StatusCode: http.StatusBadGateway}), ShouldBeTrue)
+		So(IsTransient(&ResponseError{StatusCode: http.StatusTooManyRequests}), ShouldBeTrue)
+		So(IsTransient(&ResponseError{StatusCode: http.StatusBadRequest}), ShouldBeFalse)
+		So(IsTransient(fmt.Errorf("get job: %w", &ResponseError{StatusCode: http.StatusServiceUnavailable})), ShouldBeTrue)
+		So(IsTransient(context.Canceled), ShouldBeFalse)
+		So(IsTransient(errors.New("invalid job")), ShouldBeFalse)
+		So(IsTransient(nil), ShouldBeFalse)
+
+		_, err := newClient("http://127.0.0.1:1", 0).GetJob(context.Background(), "test")
+		So(IsTransient(err), ShouldBeTrue)
+	})
+}
@@ -0,0 +1,52 @@
+package nomadclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ResponseError is an error implementation that includes the body and status
+// code of the response.
+type ResponseError struct {
+	Body       string
+	StatusCode int
+	URL        string
+}
+
+func (e *ResponseError) Error() string {
+	return "unexpected response from client"
+}
+
+// TokenError is an error implementation that includes the Vault role that a
+// Nomad token could not be read for.
+type TokenError struct {
+	Role string
+}
+
+func (e *TokenError) Error() string {
+	return "no nomad token for role"
+}
+
+// IsNotFound returns whether the error is a Nomad response for something that
+// does not exist.
+func IsNotFound(err error) bool {
+	var re *ResponseError
+	return errors.As(err, &re) && re.StatusCode == http.StatusNotFound
+}
+
+// IsTransient returns whether the error is a Nomad server error, rate limit or
+// connection error that may succeed if the request is retried. Cancelled
+// requests are not transient.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var re *ResponseError
+	if errors.As(err, &re) {
+		return re.StatusCode >= http.StatusInternalServerError || re.StatusCode == http.StatusTooManyRequests
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
@@ -0,0 +1,52 @@
+package nomadclient
+
+import (
+	"context"
+
+	"github.com/hashicorp/nomad/api"
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+//go:generate moq -out clientmock.go . Client
+//go:generate moq -out vaultmock_test.go . VaultClient
+
+// Client is an interface to represent the Nomad API methods called to plan,
+// register and monitor jobs.
+type Client interface {
+	Target(namespace, region string) Client
+	WithToken(token TokenSource) Client
+	ListJobs(ctx context.Context) ([]api.JobListStub, error)
+	GetJob(ctx context.Context, jobID string) (*api.Job, error)
+	GetJobVersions(ctx context.Context, jobID string) (*api.JobVersionsResponse, error)
+	ParseJob(ctx context.Context, req *api.JobsParseRequest) (*api.Job, error)
+	PlanJob(ctx context.Context, jobID string, job *api.Job, diff bool) (*api.JobPlanResponse, error)
+	RegisterJob(ctx context.Context, job *api.Job) (*api.JobRegisterResponse, error)
+	RevertJob(ctx context.Context, req *api.JobRevertRequest) (*api.JobRegisterResponse, error)
+	DeregisterJob(ctx context.Context, jobID string, purge bool) (*api.JobDeregisterResponse, error)
+	ScaleJob(ctx context.Context, jobID, group string, count int, message string) (*api.JobRegisterResponse, error)
+	ForcePeriodic(ctx context.Context, jobID string) (string, error)
+	DispatchJob(ctx context.Context, jobID string, meta map[string]string, payload []byte) (*api.JobDispatchResponse, error)
+	ListDeployments(ctx context.Context, jobID string) ([]api.Deployment, error)
+	PromoteDeployment(ctx context.Context, deploymentID string) error
+	FailDeployment(ctx context.Context, deploymentID string) error
+	ListAllocations(ctx context.Context, jobID string) ([]api.AllocationListStub, error)
+	GetAllocation(ctx context.Context, allocationID string) (*api.Allocation, error)
+	RestartAllocation(ctx context.Context, allocationID string) error
+	GetAllocationChecks(ctx context.Context, allocationID string) (api.AllocCheckStatuses, error)
+	TaskLogs(ctx context.Context, allocationID, task, logType string, tail int64) ([]byte, error)
+	GetEvaluation(ctx context.Context, evaluationID string) (*api.Evaluation, error)
+	Health(ctx context.Context) error
+}
+
+// TokenSource is an interface to represent where the ACL tokens that authorise
+// Nomad requests come from
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// VaultClient is an interface to represent methods called to read and revoke
+// Nomad tokens from Vault
+type VaultClient interface {
+	Read(path string) (*vaultapi.Secret, error)
+	Write(path string, data map[string]interface{}) (*vaultapi.Secret, error)
+}
@@ -0,0 +1,160 @@
+package nomadclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ONSdigital/log.go/v2/log"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	credsPath  = "%s/creds/%s"
+	revokePath = "sys/leases/revoke"
+)
+
+// tokenRenewMargin is how long before its lease ends that a token is replaced,
+// so that requests are not made with a token that is about to expire.
+var tokenRenewMargin = time.Minute
+
+// StaticToken is a TokenSource that always returns the same token.
+type StaticToken string
+
+// Token returns the token.
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	return string(t), nil
+}
+
+// VaultTokens is a cache of Nomad ACL tokens from Vault's Nomad secrets engine,
+// keyed by the role they were created for. Tokens are read from Vault without
+// holding the cache lock, and only one read is made at a time for each role.
+type VaultTokens struct {
+	client VaultClient
+	mount  string
+	group  singleflight.Group
+	mu     sync.Mutex
+	tokens map[string]*vaultToken
+}
+
+type vaultToken struct {
+	secretID string
+	leaseID  string
+	renewAt  time.Time
+}
+
+// NewVaultTokens returns a cache of tokens from the Nomad secrets engine mounted
+// at the path.
+func NewVaultTokens(vc VaultClient, mount string) *VaultTokens {
+	return &VaultTokens{client: vc, mount: mount, tokens: make(map[string]*vaultToken)}
+}
+
+// Role returns a source of tokens for the role.
+func (v *VaultTokens) Role(role string) TokenSource {
+	return &roleTokens{tokens: v, role: role}
+}
+
+// token returns the cached token for the role, creating a new one if there
+// isn't one or its lease is about to end.
+func (v *VaultTokens) token(ctx context.Context, role string) (string, error) {
+	if t := v.cached(role); t != nil {
+		return t.secretID, nil
+	}
+	secretID, err, _ := v.group.Do(role, func() (interface{}, error) {
+		if t := v.cached(role); t != nil {
+			return t.secretID, nil
+		}
+		return v.create(ctx, role)
+	})
+	if err != nil {
+		return "", err
+	}
+	return secretID.(string), nil
+}
+
+// cached returns the cached token for the role, or nil if there isn't one or
+// its lease is about to end.
+func (v *VaultTokens) cached(role string) *vaultToken {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if t, ok := v.tokens[role]; ok && (t.renewAt.IsZero() || time.Now().Before(t.renewAt)) {
+		return t
+	}
+	return nil
+}
+
+// create reads a new token for the role from Vault and caches it. The lease of
+// the token it replaces is revoked.
+func (v *VaultTokens) create(ctx context.Context, role string) (string, error) {
+	s, err := v.client.Read(fmt.Sprintf(credsPath, v.mount, role))
+	if err != nil {
+		return "", err
+	}
+	if s == nil {
+		return "", &TokenError{Role: role}
+	}
+	secretID, _ := s.Data["secret_id"].(string)
+	if len(secretID) == 0 {
+		return "", &TokenError{Role: role}
+	}
+
+	t := &vaultToken{secretID: secretID, leaseID: s.LeaseID}
+	if s.LeaseDuration > 0 {
+		lease := time.Duration(s.LeaseDuration) * time.Second
+		margin := tokenRenewMargin
+		if margin > lease/2 {
+			margin = lease / 2
+		}
+		t.renewAt = time.Now().Add(lease - margin)
+	}
+
+	v.mu.Lock()
+	replaced := v.tokens[role]
+	v.tokens[role] = t
+	v.mu.Unlock()
+	log.Info(ctx, "nomad token created", log.Data{"role": role, "lease_duration": s.LeaseDuration})
+
+	if replaced != nil {
+		v.revoke(ctx, role, replaced)
+	}
+	return secretID, nil
+}
+
+// Revoke revokes the leases of the cached tokens so that they can't be used
+// once the deployer has shut down. Errors are logged as the tokens expire at the
+// end of their lease anyway.
+func (v *VaultTokens) Revoke(ctx context.Context) {
+	v.mu.Lock()
+	tokens := v.tokens
+	v.tokens = make(map[string]*vaultToken)
+	v.mu.Unlock()
+
+	for role, t := range tokens {
+		v.revoke(ctx, role, t)
+	}
+}
+
+// revoke revokes the token's lease, logging any error.
+func (v *VaultTokens) revoke(ctx context.Context, role string, t *vaultToken) {
+	if len(t.leaseID) == 0 {
+		return
+	}
+	if _, err := v.client.Write(revokePath, map[string]interface{}{"lease_id": t.leaseID}); err != nil {
+		log.Error(ctx, "VaultTokens-revoke, v.client.Write() error", err, log.Data{"role": role})
+		return
+	}
+	log.Info(ctx, "nomad token revoked", log.Data{"role": role})
+}
+
+// roleTokens is a TokenSource for one of the roles in a VaultTokens cache.
+type roleTokens struct {
+	tokens *VaultTokens
+	role   string
+}
+
+// Token returns the cached token for the role.
+func (r *roleTokens) Token(ctx context.Context) (string, error) {
+	return r.tokens.token(ctx, r.role)
+}
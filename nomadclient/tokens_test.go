@@ -0,0 +1,143 @@
+package nomadclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	dphttp "github.com/ONSdigital/dp-net/v2/http"
+	vaultapi "github.com/hashicorp/vault/api"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestVaultTokens(t *testing.T) {
+	newVault := func(leaseDuration int) *VaultClientMock {
+		return &VaultClientMock{
+			ReadFunc: func(path string) (*vaultapi.Secret, error) {
+				switch path {
+				case "nomad/creds/deployer":
+					return &vaultapi.Secret{LeaseID: "nomad/creds/deployer/1", LeaseDuration: leaseDuration, Data: map[string]interface{}{"secret_id": "token"}}, nil
+				case "nomad/creds/broken":
+					return nil, errors.New("vault error")
+				}
+				return nil, nil
+			},
+			WriteFunc: func(path string, data map[string]interface{}) (*vaultapi.Secret, error) {
+				return nil, nil
+			},
+		}
+	}
+
+	Convey("tokens are read from vault and cached", t, func() {
+		vc := newVault(3600)
+		tokens := NewVaultTokens(vc, "nomad")
+
+		token, err := tokens.Role("deployer").Token(context.Background())
+		So(err, ShouldBeNil)
+		So(token, ShouldEqual, "token")
+		token, err = tokens.Role("deployer").Token(context.Background())
+		So(err, ShouldBeNil)
+		So(token, ShouldEqual, "token")
+		So(vc.ReadCalls(), ShouldHaveLength, 1)
+
+		Convey("and their leases are revoked", func() {
+			tokens.Revoke(context.Background())
+			So(vc.WriteCalls(), ShouldHaveLength, 1)
+			So(vc.WriteCalls()[0].Path, ShouldEqual, "sys/leases/revoke")
+			So(vc.WriteCalls()[0].Data, ShouldResemble, map[string]interface{}{"lease_id": "nomad/creds/deployer/1"})
+
+			tokens.Revoke(context.Background())
+			So(vc.WriteCalls(), ShouldHaveLength, 1)
+		})
+	})
+
+	Convey("tokens are replaced shortly before their lease ends", t, func() {
+		defaultMargin := tokenRenewMargin
+		tokenRenewMargin = time.Second
+		defer func() { tokenRenewMargin = defaultMargin }()
+
+		vc := newVault(1)
+		tokens := NewVaultTokens(vc, "nomad")
+		_, err := tokens.Role("deployer").Token(context.Background())
+		So(err, ShouldBeNil)
+		time.Sleep(time.Millisecond * 600)
+		_, err = tokens.Role("deployer").Token(context.Background())
+		So(err, ShouldBeNil)
+		So(vc.ReadCalls(), ShouldHaveLength, 2)
+
+		Convey("and the replaced token's lease is revoked", func() {
+			So(vc.WriteCalls(), ShouldHaveLength, 1)
+			So(vc.WriteCalls()[0].Path, ShouldEqual, "sys/leases/revoke")
+			So(vc.WriteCalls()[0].Data, ShouldResemble, map[string]interface{}{"lease_id": "nomad/creds/deployer/1"})
+		})
+	})
+
+	Convey("concurrent requests for a role share one vault read", t, func() {
+		release := make(chan struct{})
+		vc := newVault(3600)
+		read := vc.ReadFunc
+		vc.ReadFunc = func(path string) (*vaultapi.Secret, error) {
+			if path == "nomad/creds/deployer" {
+				<-release
+			}
+			return read(path)
+		}
+		tokens := NewVaultTokens(vc, "nomad")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				tokens.Role("deployer").Token(context.Background())
+			}()
+		}
+		time.Sleep(time.Millisecond * 100)
+
+		// other roles are not blocked by the read in flight
+		_, err := tokens.Role("broken").Token(context.Background())
+		So(err, ShouldResemble, errors.New("vault error"))
+
+		close(release)
+		wg.Wait()
+		deployer := 0
+		for _, call := range vc.ReadCalls() {
+			if call.Path == "nomad/creds/deployer" {
+				deployer++
+			}
+		}
+		So(deployer, ShouldEqual, 1)
+	})
+
+	Convey("roles without a token are errors", t, func() {
+		tokens := NewVaultTokens(newVault(3600), "nomad")
+
+		_, err := tokens.Role("unknown").Token(context.Background())
+		So(err, ShouldResemble, &TokenError{Role: "unknown"})
+
+		_, err = tokens.Role("broken").Token(context.Background())
+		So(err, ShouldResemble, errors.New("vault error"))
+	})
+
+	Convey("requests are authorised with the role's token", t, func() {
+		var token string
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			token = req.Header.Get("X-Nomad-Token")
+			w.Write([]byte(`{"ID": "test"}`))
+		}))
+		defer s.Close()
+
+		c := New(s.URL, "static", dphttp.NewClient(), 0, 0)
+		_, err := c.WithToken(NewVaultTokens(newVault(3600), "nomad").Role("deployer")).GetJob(context.Background(), "test")
+		So(err, ShouldBeNil)
+		So(token, ShouldEqual, "token")
+
+		_, err = c.GetJob(context.Background(), "test")
+		So(err, ShouldBeNil)
+		So(token, ShouldEqual, "static")
+	})
+}
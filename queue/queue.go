@@ -72,6 +72,7 @@ type HandlerFunc func(ctx context.Context, cfg config.Configuration, msg *messag
 type response struct {
 	Error   *responseError `json:"Error,omitempty"`
 	ID      string
+	Result  interface{} `json:"Result,omitempty"`
 	Success bool
 }
 
@@ -179,33 +180,33 @@ func (q *Queue) handle(ctx context.Context, rawMsg *ssqs.Message) {
 			<-q.semaphore
 		}()
 
-		m, err := q.verifyMessage(rawMsg)
+		m, signer, err := q.verifyMessage(rawMsg)
 		if err != nil {
-			q.postHandle(ctx, rawMsg, err)
+			q.postHandle(ctx, rawMsg, nil, err)
 			return
 		}
 
-		queueMsg := message.MessageSQS{Job: rawMsg.ID} // replace this with messageSQS
+		queueMsg := message.MessageSQS{ID: rawMsg.ID, Job: rawMsg.ID, Signer: signer} // replace this with messageSQS
 		if err := json.Unmarshal(m, &queueMsg); err != nil {
-			q.postHandle(ctx, rawMsg, err)
+			q.postHandle(ctx, rawMsg, nil, err)
 			return
 		}
 
 		if err := q.handlers(ctx, *q.config, &queueMsg); err != nil {
-			q.postHandle(ctx, rawMsg, err)
+			q.postHandle(ctx, rawMsg, queueMsg.Result, err)
 			return
 		}
 
-		q.postHandle(ctx, rawMsg, nil)
+		q.postHandle(ctx, rawMsg, queueMsg.Result, nil)
 	}()
 }
 
-func (q *Queue) postHandle(ctx context.Context, msg *ssqs.Message, err error) {
+func (q *Queue) postHandle(ctx context.Context, msg *ssqs.Message, res interface{}, err error) {
 	if err != nil {
 		ErrHandler(ctx, "post handle error", err)
 	}
 
-	result := &response{ID: msg.ID, Success: err == nil}
+	result := &response{ID: msg.ID, Result: res, Success: err == nil}
 	if err != nil {
 		result.Error = &responseError{Data: err, Message: err.Error()}
 	}
@@ -262,13 +263,16 @@ func (q *Queue) sendMessage(ctx context.Context, body string) error {
 	return nil
 }
 
-func (q *Queue) verifyMessage(rawMsg *ssqs.Message) ([]byte, error) {
+// verifyMessage returns the plaintext of the signed message and the key ID of
+// its signer.
+func (q *Queue) verifyMessage(rawMsg *ssqs.Message) ([]byte, string, error) {
 	decoded, _ := clearsign.Decode([]byte(rawMsg.Body))
 	if decoded == nil {
-		return nil, &InvalidBlockError{rawMsg.ID}
+		return nil, "", &InvalidBlockError{rawMsg.ID}
 	}
-	if _, err := openpgp.CheckDetachedSignature(q.keyring, bytes.NewReader(decoded.Bytes), decoded.ArmoredSignature.Body); err != nil {
-		return nil, err
+	signer, err := openpgp.CheckDetachedSignature(q.keyring, bytes.NewReader(decoded.Bytes), decoded.ArmoredSignature.Body)
+	if err != nil {
+		return nil, "", err
 	}
-	return decoded.Plaintext, nil
+	return decoded.Plaintext, signer.PrimaryKey.KeyIdString(), nil
 }
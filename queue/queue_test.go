@@ -360,6 +360,29 @@ func TestStart(t *testing.T) {
 					So(pMessage, ShouldEqual, `{"ID":"200","Success":true}`)
 				})
 			})
+
+			Convey("handler results are propogated as expected", func() {
+				withMocks(false, validMessage, func(producer *mockProducer) {
+					q, err := New(ctx, &config.Configuration{ConsumerQueueNew: "foo", ConsumerQueueURLNew: "bar", ProducerQueue: "baz", AWSRegion: "qux", VerificationKey: publicKey}, handlerFuncMock)
+					So(q, ShouldNotBeNil)
+					So(err, ShouldBeNil)
+
+					hfunction := func(ctx context.Context, cfg config.Configuration, msg *message.MessageSQS) error {
+						msg.Result = map[string]string{"foo": "bar"}
+						return nil
+					}
+					q.handlers = hfunction
+					ErrHandler = defaultErrHandler
+
+					ctx, cancel := context.WithCancel(context.Background())
+					go time.AfterFunc(time.Second*1, cancel)
+					q.Start(ctx)
+					producer.mu.Lock()
+					pMessage := producer.message
+					producer.mu.Unlock()
+					So(pMessage, ShouldEqual, `{"ID":"200","Result":{"foo":"bar"},"Success":true}`)
+				})
+			})
 		})
 	})
 }
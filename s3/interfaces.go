@@ -1,10 +1,22 @@
 package s3
 
-import "io"
+import (
+	"io"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
 
 //go:generate moq -out s3mock.go . Client
+//go:generate moq -out uploadermock.go . Uploader
 
 // Client is an interface to represent methods called to action upon S3
 type Client interface {
 	Get(key string) (io.ReadCloser, *int64, error)
 }
+
+// Uploader is an interface to represent methods called to upload to S3
+type Uploader interface {
+	Client
+	BucketName() string
+	Upload(input *s3manager.UploadInput, options ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error)
+}
@@ -0,0 +1,33 @@
+package s3
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+)
+
+// Lister lists the keys in an S3 bucket.
+type Lister struct {
+	bucket string
+	client *awss3.S3
+}
+
+// NewLister returns a new lister for the bucket.
+func NewLister(s *session.Session, bucket string) *Lister {
+	return &Lister{bucket: bucket, client: awss3.New(s)}
+}
+
+// List returns every key in the bucket with the given prefix.
+func (l *Lister) List(prefix string) ([]string, error) {
+	var keys []string
+	err := l.client.ListObjectsV2Pages(&awss3.ListObjectsV2Input{
+		Bucket: aws.String(l.bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *awss3.ListObjectsV2Output, _ bool) bool {
+		for _, o := range page.Contents {
+			keys = append(keys, aws.StringValue(o.Key))
+		}
+		return true
+	})
+	return keys, err
+}